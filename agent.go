@@ -2,64 +2,258 @@ package agentkit
 
 import (
 	"context"
+	"errors"
+	"io"
+	"time"
 
 	"github.com/RafaelZelak/agentkit/internal/agent"
 	"github.com/RafaelZelak/agentkit/internal/memory"
 	"github.com/RafaelZelak/agentkit/internal/openai"
+	"github.com/RafaelZelak/agentkit/internal/tokenizer"
 	"github.com/RafaelZelak/agentkit/internal/tools"
 )
 
+// StreamEvent é o evento entregue por Agent.RunStream.
+type StreamEvent = agent.StreamEvent
+
+// Result é o retorno de Run e RouteAndRun. String() devolve o texto final, ou o
+// JSON completo quando o agent foi criado com verbose=true.
+type (
+	Result     = agent.Result
+	ToolCall   = agent.ToolCall
+	Route      = agent.Route
+	RouteScore = agent.RouteScore
+	Usage      = openai.Usage
+)
+
+// NoRouteError é devolvido por RouteAndRun quando o router não chega a uma rota
+// e RouterOnNoRoute é "error". Use errors.Is(err, ErrNoRoute) ou errors.As.
+type NoRouteError = agent.NoRouteError
+
+var ErrNoRoute = agent.ErrNoRoute
+
+const healthCheckTimeout = 10 * time.Second
+
+// ContextBudget limita os tokens de cada seção do contexto; ContextReport, em
+// Result.Context, mostra o que foi cortado.
+type (
+	ContextBudget  = agent.ContextBudget
+	ContextReport  = agent.ContextReport
+	ContextSection = agent.ContextSection
+)
+
+// WithSessionAttributes anexa ao contexto atributos da sessão (ex.: tenant_id)
+// usados pelos filtros das tools postgres_embedding como $session.nome. Passe o
+// contexto devolvido para Run, RunStream ou RouteAndRun.
+func WithSessionAttributes(ctx context.Context, attrs map[string]string) context.Context {
+	return tools.WithAttributes(ctx, attrs)
+}
+
+// ToolPoolConfig limita o pool de conexões das tools postgres de um DSN.
+type ToolPoolConfig = tools.PoolConfig
+
 type Agent struct {
-	cli     *openai.Client
+	rt      *agent.Runtime
 	cfg     *Config
 	verbose bool
+	// memória criada por NewAgent (não veio em Config.Memory), fechada em Close
+	ownMemory io.Closer
 }
 
+// NewAgent monta um agent com catálogo de tools, registry de scripts e memória
+// próprios. Scripts registrados via sdk.RegisterScript ficam visíveis para
+// todos os agents; Agent.RegisterScript registra só neste.
+//
+// Os bancos das tools postgres são verificados aqui; um DSN inacessível faz
+// NewAgent falhar. Chame Close ao encerrar para liberar as conexões.
 func NewAgent(cfg *Config, verbose bool) (*Agent, error) {
-	if err := tools.LoadTools(cfg.ToolsPath); err != nil {
+	catalog, err := tools.LoadTools(cfg.ToolsPath, nil)
+	if err != nil {
 		return nil, err
 	}
+	catalog.Pools.FillDefaults(cfg.ToolPool)
 
-	if _, err := memory.Init(memory.Config{
-		DSN:          cfg.DSN,
-		Schema:       cfg.Schema,
-		EmbeddingDim: cfg.EmbeddingDim,
-	}); err != nil {
+	mem := cfg.Memory
+	if mem == nil {
+		mem, err = memory.New(memory.Config{
+			Backend:      cfg.MemoryBackend,
+			SQLitePath:   cfg.SQLitePath,
+			SQLiteDriver: cfg.SQLiteDriver,
+			DSN:          cfg.DSN,
+			Schema:       cfg.Schema,
+			EmbeddingDim: cfg.EmbeddingDim,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var ownMemory io.Closer
+	if cfg.Memory == nil {
+		ownMemory, _ = mem.(io.Closer)
+	}
+	fail := func(err error) (*Agent, error) {
+		catalog.Pools.Close()
+		if ownMemory != nil {
+			ownMemory.Close()
+		}
 		return nil, err
 	}
 
-	cli := openai.NewClient(cfg.APIKey)
+	// tools no mesmo banco da memória usam o pool dela
+	if pg, ok := mem.(*memory.PostgresStore); ok {
+		catalog.Pools.Share(cfg.DSN, pg.DB())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+	if err := catalog.Ping(ctx); err != nil {
+		return fail(err)
+	}
+
+	tok, err := tokenizer.New(cfg.TokenizerFile)
+	if err != nil {
+		return fail(err)
+	}
+
+	var cli openai.Provider = cfg.Provider
+	if cli == nil {
+		cli = openai.NewClient(cfg.APIKey, cfg.clientOptions()...)
+	}
 
 	return &Agent{
-		cli:     cli,
-		cfg:     cfg,
-		verbose: verbose,
+		rt: &agent.Runtime{
+			LLM:       cli,
+			Memory:    mem,
+			Tools:     catalog,
+			Tokenizer: tok,
+			Logger:    cfg.Logger,
+		},
+		cfg:       cfg,
+		verbose:   verbose,
+		ownMemory: ownMemory,
 	}, nil
 }
 
-func (a *Agent) Run(ctx context.Context, sessionID, basePromptPath, userMessage string) (string, error) {
-	return agent.Run(
+// Close fecha os pools de conexão das tools e a memória criada por NewAgent. Um
+// store passado em Config.Memory continua aberto.
+func (a *Agent) Close() error {
+	err := a.rt.Tools.Pools.Close()
+	if a.ownMemory != nil {
+		err = errors.Join(err, a.ownMemory.Close())
+	}
+	return err
+}
+
+// RegisterScript registra um script visível apenas para este agent. Tem
+// prioridade sobre um script de mesmo nome registrado via sdk.RegisterScript.
+func (a *Agent) RegisterScript(name string, fn func(args ...string) (string, error)) {
+	a.rt.Tools.Scripts.Register(name, fn)
+}
+
+func (a *Agent) options() []agent.Option {
+	var opts []agent.Option
+	if a.cfg.ToolMode == "legacy" {
+		opts = append(opts, agent.WithLegacyToolProtocol())
+	}
+	if a.cfg.StrictToolAllowlist {
+		opts = append(opts, agent.WithStrictToolAllowlist())
+	}
+	if a.cfg.MaxSteps > 0 {
+		opts = append(opts, agent.WithMaxSteps(a.cfg.MaxSteps))
+	}
+	if a.cfg.MaxToolCalls > 0 {
+		opts = append(opts, agent.WithMaxToolCalls(a.cfg.MaxToolCalls))
+	}
+	if a.cfg.SummaryThreshold != 0 {
+		opts = append(opts, agent.WithSummaryThreshold(a.cfg.SummaryThreshold))
+	}
+	if a.cfg.RouterMode != "" {
+		opts = append(opts, agent.WithRouterMode(a.cfg.RouterMode))
+	}
+	if a.cfg.RouterTieMargin > 0 {
+		opts = append(opts, agent.WithRouterTieBreak(a.cfg.RouterTieMargin))
+	}
+	if a.cfg.RouterThreshold > 0 {
+		opts = append(opts, agent.WithRouterThreshold(a.cfg.RouterThreshold, a.cfg.RouterOnLowConfidence))
+	}
+	if a.cfg.RouterSwitchConfidence > 0 {
+		opts = append(opts, agent.WithStickyRouting(a.cfg.RouterSwitchConfidence))
+	}
+	if a.cfg.RouterFallback != "" {
+		opts = append(opts, agent.WithRouterFallback(a.cfg.RouterFallback))
+	}
+	if a.cfg.RouterRetries != 0 {
+		opts = append(opts, agent.WithRouterRetries(a.cfg.RouterRetries))
+	}
+	if a.cfg.RouterOnNoRoute != "" {
+		opts = append(opts, agent.WithRouterOnNoRoute(a.cfg.RouterOnNoRoute))
+	}
+	if a.cfg.ContextBudget != (ContextBudget{}) {
+		opts = append(opts, agent.WithContextBudget(a.cfg.ContextBudget))
+	}
+	return opts
+}
+
+func (a *Agent) Run(ctx context.Context, sessionID, basePromptPath, userMessage string) (*Result, error) {
+	res, err := agent.Run(
+		ctx,
+		a.rt,
+		a.cfg.GPTModel,
+		a.cfg.EmbModel,
+		sessionID,
+		basePromptPath,
+		userMessage,
+		a.options()...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	res.Verbose = a.verbose
+	return res, nil
+}
+
+// RunStream entrega a resposta em deltas conforme o modelo gera. O último evento
+// é "done" (mensagens já salvas na memória) ou "error".
+func (a *Agent) RunStream(ctx context.Context, sessionID, basePromptPath, userMessage string) (<-chan StreamEvent, error) {
+	return agent.RunStream(
 		ctx,
-		a.cli,
+		a.rt,
 		a.cfg.GPTModel,
 		a.cfg.EmbModel,
 		sessionID,
 		basePromptPath,
 		userMessage,
-		a.verbose,
+		a.options()...,
 	)
 }
 
-func (a *Agent) RouteAndRun(ctx context.Context, sessionID, basePromptPath, userMessage, routerPath string) (string, error) {
-	return agent.RouteAndRun(
+func (a *Agent) RouteAndRun(ctx context.Context, sessionID, basePromptPath, userMessage, routerPath string) (*Result, error) {
+	res, err := agent.RouteAndRun(
 		ctx,
-		a.cli,
+		a.rt,
 		a.cfg.GPTModel,
 		a.cfg.EmbModel,
 		sessionID,
 		basePromptPath,
 		userMessage,
 		routerPath,
-		a.verbose,
+		a.options()...,
 	)
+	if err != nil {
+		return nil, err
+	}
+	res.Verbose = a.verbose
+	return res, nil
+}
+
+// SessionRoute devolve a rota atual da sessão para o router, ou nil se ainda
+// não houver.
+func (a *Agent) SessionRoute(ctx context.Context, sessionID, routerPath string) (*SessionRoute, error) {
+	return agent.CurrentRoute(ctx, a.rt, sessionID, routerPath)
+}
+
+// SetSessionRoute fixa a rota da sessão (ex.: "financeiro.md"); enquanto fixada o
+// router não é consultado. Com prompt vazio a sessão volta a ser roteada.
+func (a *Agent) SetSessionRoute(ctx context.Context, sessionID, routerPath, prompt string) error {
+	return agent.ForceRoute(ctx, a.rt, sessionID, routerPath, prompt)
 }
@@ -0,0 +1,63 @@
+// Command agentkit-ingest popula a tabela de uma tool postgres_embedding com os
+// documentos de um diretório. A configuração vem do ambiente, como em
+// agentkit.NewConfigFromEnv:
+//
+//	agentkit-ingest -tool docs_search -dir ./docs -prune
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/RafaelZelak/agentkit"
+)
+
+func main() {
+	var (
+		tool    = flag.String("tool", "", "tool postgres_embedding de destino (obrigatório)")
+		dir     = flag.String("dir", ".", "diretório com os documentos")
+		chunk   = flag.Int("chunk-tokens", 0, "tamanho máximo dos trechos em tokens (padrão 500)")
+		overlap = flag.Int("overlap-tokens", 0, "sobreposição entre trechos em tokens (padrão 50, negativo desliga)")
+		batch   = flag.Int("batch", 0, "textos por requisição de embedding (padrão 64)")
+		prune   = flag.Bool("prune", false, "apaga da tabela os documentos que não existem mais no diretório")
+		dim     = flag.Int("dim", 0, "dimensão do vetor ao criar a tabela (padrão: a do modelo)")
+	)
+	flag.Parse()
+	if *tool == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	rep, err := run(*tool, *dir, agentkit.IngestOptions{
+		ChunkTokens:   *chunk,
+		OverlapTokens: *overlap,
+		BatchSize:     *batch,
+		Prune:         *prune,
+		Dim:           *dim,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	out, _ := json.MarshalIndent(rep, "", "  ")
+	os.Stdout.Write(append(out, '\n'))
+}
+
+func run(tool, dir string, opts agentkit.IngestOptions) (*agentkit.IngestReport, error) {
+	cfg, err := agentkit.NewConfigFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	if cfg == nil {
+		return nil, errors.New("config: EMBEDDING_DIM precisa ser maior que zero")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return agentkit.Ingest(ctx, cfg, tool, dir, opts)
+}
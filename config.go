@@ -1,8 +1,14 @@
 package agentkit
 
 import (
+	"log/slog"
+	"net/http"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RafaelZelak/agentkit/internal/openai"
 )
 
 type Config struct {
@@ -13,6 +19,68 @@ type Config struct {
 	GPTModel     string
 	EmbModel     string
 	ToolsPath    string
+	ToolMode     string // "native" (padrão) ou "legacy"
+	MaxSteps     int    // 0 = AGENT_MAX_STEPS ou 5
+	MaxToolCalls int    // 0 = AGENT_MAX_TOOL_CALLS ou 8
+
+	// Limites dos pools de conexão das tools postgres, um por DSN. O "pool" do
+	// tools.yml e o de cada tool têm prioridade.
+	ToolPool ToolPoolConfig
+
+	// StrictToolAllowlist faz prompts sem "tools:" no front matter não poderem
+	// usar nenhuma tool (padrão: liberam todas)
+	StrictToolAllowlist bool
+
+	// Logger recebe os avisos do agent, como tools recusadas pela allowlist;
+	// nil usa slog.Default()
+	Logger *slog.Logger
+
+	// Memória: "postgres" (padrão), "memory" ou "sqlite"
+	MemoryBackend string
+	SQLitePath    string
+	SQLiteDriver  string      // driver registrado em database/sql, padrão "sqlite"
+	Memory        MemoryStore // store próprio; ignora MemoryBackend
+
+	// Mensagens fora da memória curta que disparam o resumo da sessão
+	// (0 = MEM_SUMMARY_THRESHOLD ou 20, negativo desliga)
+	SummaryThreshold int
+
+	// Orçamento de tokens do contexto. TokenizerFile aponta para um vocabulário
+	// .tiktoken (ex.: o200k_base.tiktoken); vazio usa uma estimativa.
+	TokenizerFile string
+	ContextBudget ContextBudget
+
+	// Router: "llm" (padrão) ou "embedding". RouterTieMargin > 0 faz o modo
+	// embedding pedir desempate ao LLM quando os dois melhores estão próximos.
+	RouterMode      string
+	RouterTieMargin float64
+
+	// Confiança mínima da rota escolhida (0 desliga). Abaixo dela usa o
+	// fallback ou, com RouterOnLowConfidence = "clarify", pergunta ao usuário.
+	RouterThreshold       float64
+	RouterOnLowConfidence string
+
+	// Routing fixo por sessão: a rota atual só muda quando o router escolhe
+	// outra com confiança >= RouterSwitchConfidence (0 desliga).
+	RouterSwitchConfidence float64
+
+	// Sem rota: prompt de fallback (padrão geral.md), quantas vezes repetir a
+	// pergunta após resposta inválida (0 = 1, negativo não repete) e "fallback"
+	// ou "error" (RouteAndRun devolve *NoRouteError). O front matter do
+	// router.md tem prioridade.
+	RouterFallback  string
+	RouterRetries   int
+	RouterOnNoRoute string
+
+	// Client da OpenAI
+	BaseURL      string            // padrão https://api.openai.com/v1
+	Organization string            // header OpenAI-Organization
+	Project      string            // header OpenAI-Project
+	Headers      map[string]string // headers extras em todas as requisições
+	Transport    http.RoundTripper
+
+	// Provider substitui o client da OpenAI criado a partir de APIKey.
+	Provider Provider
 }
 
 func NewConfigFromEnv() (*Config, error) {
@@ -23,17 +91,94 @@ func NewConfigFromEnv() (*Config, error) {
 	}
 
 	cfg := &Config{
-		APIKey:       os.Getenv("OPENAI_API_KEY"),
-		DSN:          os.Getenv("PGSQL"),
-		Schema:       os.Getenv("DB_SCHEMA"),
-		EmbeddingDim: embDim,
-		GPTModel:     os.Getenv("GPT_MODEL"),
-		EmbModel:     os.Getenv("EMBEDDING_MODEL"),
-		ToolsPath:    os.Getenv("TOOLS_PATH"),
+		APIKey:        os.Getenv("OPENAI_API_KEY"),
+		DSN:           os.Getenv("PGSQL"),
+		Schema:        os.Getenv("DB_SCHEMA"),
+		EmbeddingDim:  embDim,
+		GPTModel:      os.Getenv("GPT_MODEL"),
+		EmbModel:      os.Getenv("EMBEDDING_MODEL"),
+		ToolsPath:     os.Getenv("TOOLS_PATH"),
+		ToolMode:      os.Getenv("TOOL_MODE"),
+		MemoryBackend: os.Getenv("MEMORY_BACKEND"),
+		SQLitePath:    os.Getenv("SQLITE_PATH"),
+		SQLiteDriver:  os.Getenv("SQLITE_DRIVER"),
+		TokenizerFile: os.Getenv("TOKENIZER_FILE"),
+		RouterMode:    os.Getenv("ROUTER_MODE"),
+
+		RouterOnLowConfidence: os.Getenv("ROUTER_ON_LOW_CONFIDENCE"),
+		RouterFallback:        os.Getenv("ROUTER_FALLBACK"),
+		RouterOnNoRoute:       os.Getenv("ROUTER_ON_NO_ROUTE"),
+		BaseURL:               os.Getenv("OPENAI_BASE_URL"),
+		Organization:          os.Getenv("OPENAI_ORG_ID"),
+		Project:               os.Getenv("OPENAI_PROJECT_ID"),
+		Headers:               parseHeaders(os.Getenv("OPENAI_EXTRA_HEADERS")),
 	}
 
+	if v, err := strconv.ParseFloat(os.Getenv("ROUTER_TIE_MARGIN"), 64); err == nil {
+		cfg.RouterTieMargin = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("ROUTER_THRESHOLD"), 64); err == nil {
+		cfg.RouterThreshold = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("ROUTER_SWITCH_CONFIDENCE"), 64); err == nil {
+		cfg.RouterSwitchConfidence = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("ROUTER_RETRIES")); err == nil {
+		cfg.RouterRetries = v
+	}
+
+	if v, err := strconv.Atoi(os.Getenv("TOOL_DB_MAX_OPEN_CONNS")); err == nil {
+		cfg.ToolPool.MaxOpenConns = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("TOOL_DB_MAX_IDLE_CONNS")); err == nil {
+		cfg.ToolPool.MaxIdleConns = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("TOOL_DB_CONN_MAX_LIFETIME")); err == nil {
+		cfg.ToolPool.ConnMaxLifetime = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("TOOL_DB_CONN_MAX_IDLE_TIME")); err == nil {
+		cfg.ToolPool.ConnMaxIdleTime = v
+	}
+
+	cfg.StrictToolAllowlist = os.Getenv("TOOL_ALLOWLIST") == "strict"
+
 	if cfg.ToolsPath == "" {
 		cfg.ToolsPath = "tools.yml"
 	}
 	return cfg, nil
 }
+
+func (c *Config) clientOptions() []openai.ClientOption {
+	opts := []openai.ClientOption{
+		openai.WithBaseURL(c.BaseURL),
+		openai.WithOrganization(c.Organization),
+		openai.WithProject(c.Project),
+		openai.WithTransport(c.Transport),
+	}
+	for k, v := range c.Headers {
+		opts = append(opts, openai.WithHeader(k, v))
+	}
+	return opts
+}
+
+// parseHeaders lê "Chave=valor,Outra=valor". Valores no formato ENV:VAR são
+// resolvidos no ambiente, como em tools.yml.
+func parseHeaders(s string) map[string]string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	out := map[string]string{}
+	for _, kv := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(kv, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			continue
+		}
+		v = strings.TrimSpace(v)
+		if strings.HasPrefix(v, "ENV:") {
+			v = os.Getenv(strings.TrimPrefix(v, "ENV:"))
+		}
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,59 @@
+package agentkit
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/RafaelZelak/agentkit/internal/ingest"
+	"github.com/RafaelZelak/agentkit/internal/openai"
+	"github.com/RafaelZelak/agentkit/internal/tokenizer"
+	"github.com/RafaelZelak/agentkit/internal/tools"
+)
+
+// IngestOptions controla o tamanho dos trechos, a sobreposição, o lote de
+// embeddings e se documentos removidos do diretório saem da tabela.
+type IngestOptions = ingest.Options
+
+// IngestReport resume o que Agent.Ingest fez.
+type IngestReport = ingest.Report
+
+// Ingest lê os arquivos .md, .txt, .html e .csv de dir para a tabela da tool
+// postgres_embedding toolName, usando a conexão, o modelo e as colunas dela.
+// Trechos com o mesmo conteúdo não geram embedding de novo.
+func (a *Agent) Ingest(ctx context.Context, toolName, dir string, opts IngestOptions) (*IngestReport, error) {
+	return runIngest(ctx, a.rt.Tools, a.rt.LLM, a.rt.Tokenizer, toolName, dir, opts)
+}
+
+// Ingest faz o mesmo que Agent.Ingest sem montar um agent: carrega só o
+// tools.yml, o provider de embeddings e a conexão da tool de destino. A memória
+// não é aberta e os bancos das outras tools não são acessados.
+func Ingest(ctx context.Context, cfg *Config, toolName, dir string, opts IngestOptions) (*IngestReport, error) {
+	catalog, err := tools.LoadTools(cfg.ToolsPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	catalog.Pools.FillDefaults(cfg.ToolPool)
+	defer catalog.Pools.Close()
+
+	tok, err := tokenizer.New(cfg.TokenizerFile)
+	if err != nil {
+		return nil, err
+	}
+	var cli openai.Provider = cfg.Provider
+	if cli == nil {
+		cli = openai.NewClient(cfg.APIKey, cfg.clientOptions()...)
+	}
+	return runIngest(ctx, catalog, cli, tok, toolName, dir, opts)
+}
+
+func runIngest(ctx context.Context, catalog *tools.Catalog, emb openai.Embedder, tok tokenizer.Tokenizer, toolName, dir string, opts IngestOptions) (*IngestReport, error) {
+	tc := catalog.GetTool(toolName)
+	if tc == nil {
+		return nil, fmt.Errorf("tool %s não encontrada", toolName)
+	}
+	db, err := catalog.Pools.DB(tc.Conn, tc.Pool)
+	if err != nil {
+		return nil, err
+	}
+	return ingest.Run(ctx, db, emb, tok, *tc, dir, opts)
+}
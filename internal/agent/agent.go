@@ -1,8 +1,8 @@
 package agent
 
 import (
+	"cmp"
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
 	"strconv"
@@ -11,23 +11,10 @@ import (
 
 	"github.com/RafaelZelak/agentkit/internal/memory"
 	"github.com/RafaelZelak/agentkit/internal/openai"
-	"github.com/RafaelZelak/agentkit/internal/tools"
 
 	"golang.org/x/sync/errgroup"
 )
 
-type runVerbose struct {
-	ToolRequested string   `json:"tool_requested,omitempty"`
-	ToolArgs      []string `json:"tool_args,omitempty"`
-	ToolOutput    string   `json:"tool_output,omitempty"`
-	FinalText     string   `json:"final_text"`
-}
-
-func (rv runVerbose) JSON() string {
-	js, _ := json.MarshalIndent(rv, "", "  ")
-	return string(js)
-}
-
 func envInt(key string, def int) int {
 	if v := os.Getenv(key); v != "" {
 		if n, err := strconv.Atoi(v); err == nil && n > 0 {
@@ -37,44 +24,57 @@ func envInt(key string, def int) int {
 	return def
 }
 
-func buildMemBlock(recent, similar []memory.HistoryItem, boleto map[string]string) string {
-	var sb strings.Builder
-
-	if len(recent) > 0 {
-		sb.WriteString("== Memória curta (últimas mensagens) ==\n")
-		for _, h := range recent {
-			sb.WriteString(h.Role)
-			sb.WriteString(": ")
-			sb.WriteString(h.Text)
-			sb.WriteByte('\n')
-		}
-		sb.WriteByte('\n')
+func buildMemBlock(summary *memory.Summary, recent, similar []memory.HistoryItem, facts []memory.Fact) string {
+	summaryText := ""
+	if summary != nil {
+		summaryText = summary.Text
 	}
+	return summaryBlock(summaryText) +
+		historyBlock(recentHeader, recent) +
+		factsBlock(facts) +
+		historyBlock(semanticHeader, similar)
+}
 
-	if len(boleto) > 0 {
-		sb.WriteString("== Estado estruturado (faturas) ==\n")
-		sb.WriteString("Use estes fatos como verdade a menos que o usuário informe atualização.\n")
-		for id, st := range boleto {
-			sb.WriteString("- Fatura ")
-			sb.WriteString(id)
-			sb.WriteString(": ")
-			sb.WriteString(st)
-			sb.WriteByte('\n')
-		}
-		sb.WriteByte('\n')
+// Cabeçalhos das seções do bloco de memória. O assembler conta cada seção já
+// renderizada, com cabeçalho.
+const (
+	summaryHeader  = "== Resumo da conversa até aqui ==\n"
+	recentHeader   = "== Memória curta (últimas mensagens) ==\n"
+	factsHeader    = "== Estado estruturado (fatos da sessão) ==\nUse estes fatos como verdade a menos que o usuário informe atualização.\n"
+	semanticHeader = "== Memória semântica relevante ==\n"
+)
+
+func summaryBlock(text string) string {
+	if text == "" {
+		return ""
 	}
+	return summaryHeader + text + "\n\n"
+}
 
-	if len(similar) > 0 {
-		sb.WriteString("== Memória semântica relevante ==\n")
-		for _, h := range similar {
-			sb.WriteString(h.Role)
-			sb.WriteString(": ")
-			sb.WriteString(h.Text)
-			sb.WriteByte('\n')
-		}
+func historyBlock(header string, items []memory.HistoryItem) string {
+	if len(items) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(header)
+	for _, h := range items {
+		sb.WriteString(h.Role)
+		sb.WriteString(": ")
+		sb.WriteString(h.Text)
 		sb.WriteByte('\n')
 	}
+	sb.WriteByte('\n')
+	return sb.String()
+}
 
+func factsBlock(facts []memory.Fact) string {
+	if len(facts) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(factsHeader)
+	writeFacts(&sb, facts)
+	sb.WriteByte('\n')
 	return sb.String()
 }
 
@@ -102,30 +102,42 @@ func extractToolCommand(s string) (string, bool) {
 	return "", false
 }
 
-func Run(
+// runState guarda o que foi montado antes da primeira chamada ao modelo e é
+// compartilhado entre Run e RunStream.
+type runState struct {
+	promptPath string
+	mem        memory.Store
+	userEmb    []float32
+	newReq     func(extra ...Option) *openai.ResponsesRequest
+	legacy     bool
+	limits     loopLimits
+	context    *ContextReport
+
+	model            string
+	respModel        string
+	memDepth         int
+	summaryThreshold int
+}
+
+func prepareRun(
 	ctx context.Context,
-	cli *openai.Client,
+	rt *Runtime,
 	model string,
 	embeddingModel string,
 	sessionID string,
 	promptPath string,
 	userMessage string,
-	verbose bool,
-	opts ...Option,
-) (string, error) {
-	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, 60*time.Second)
-		defer cancel()
-	}
-
-	promptBytes, err := os.ReadFile(promptPath)
+	opts []Option,
+) (*runState, error) {
+	prompt, err := loadPrompt(promptPath)
 	if err != nil {
-		return "", fmt.Errorf("read prompt: %w", err)
+		return nil, fmt.Errorf("read prompt: %w", err)
 	}
-	longPrompt := string(promptBytes)
+	longPrompt := prompt.Body
+	// o front matter do prompt base vem antes das opções, que podem sobrescrevê-lo
+	opts = append(prompt.Meta.baseOptions(), opts...)
 
-	mem := memory.Get()
+	mem := rt.Memory
 
 	semTopK := envInt("MEM_SEM_TOPK", 5)
 	memDepth := envInt("MEM_DEPTH", 4)
@@ -134,11 +146,12 @@ func Run(
 		userEmb []float32
 		similar []memory.HistoryItem
 		recent  []memory.HistoryItem
-		faturas map[string]string
+		facts   []memory.Fact
+		summary *memory.Summary
 	)
 	eg, egctx := errgroup.WithContext(ctx)
 	eg.Go(func() error {
-		emb, err := cli.Embed(egctx, embeddingModel, userMessage)
+		emb, err := rt.LLM.Embed(egctx, embeddingModel, userMessage)
 		if err != nil {
 			return err
 		}
@@ -153,9 +166,16 @@ func Run(
 		return nil
 	})
 	eg.Go(func() error {
-		m, err := mem.LoadBoletoStatus(egctx, sessionID)
+		items, err := mem.LoadFacts(egctx, sessionID)
+		if err == nil {
+			facts = items
+		}
+		return nil
+	})
+	eg.Go(func() error {
+		sum, err := mem.LoadSummary(egctx, sessionID)
 		if err == nil {
-			faturas = m
+			summary = sum
 		}
 		return nil
 	})
@@ -168,121 +188,214 @@ func Run(
 		}
 	}
 
-	memBlock := buildMemBlock(recent, similar, faturas)
-
-	b := newBuilder()
-	WithCachedContext(longPrompt)(b)
-	if memBlock != "" {
-		WithSystemPrompt(memBlock)(b)
+	cfg := applyOptions(opts)
+	fixed := 0
+	asm := newAssembler(rt.Tokenizer, cfg.budget)
+	for _, m := range cfg.system {
+		for _, c := range m.Content {
+			fixed += asm.tok.Count(c.Text)
+		}
 	}
-	for _, opt := range opts {
-		opt(b)
+	parts, report := asm.fit(contextParts{
+		system:  longPrompt,
+		fixed:   fixed,
+		summary: summary,
+		recent:  recent,
+		similar: similar,
+		facts:   facts,
+	})
+	longPrompt = parts.system
+	memBlock := buildMemBlock(parts.summary, parts.recent, parts.similar, parts.facts)
+
+	newReq := func(extra ...Option) *openai.ResponsesRequest {
+		b := newBuilder()
+		WithCachedContext(longPrompt)(b)
+		if memBlock != "" {
+			WithSystemPrompt(memBlock)(b)
+		}
+		for _, opt := range opts {
+			opt(b)
+		}
+		for _, opt := range extra {
+			opt(b)
+		}
+		b.user = openai.ContentItem{Type: "input_text", Text: userMessage}
+		req := b.req(model)
+		if !b.legacyTools {
+			req.Tools = toolDefs(rt.Tools, b.toolAllowlist())
+		}
+		return req
 	}
-	b.user = openai.ContentItem{Type: "input_text", Text: userMessage}
 
-	req := b.req(model)
-	resp, err := cli.Respond(ctx, req)
-	if err != nil {
-		return "", err
+	limits := loopLimits{
+		maxSteps:     cfg.maxSteps,
+		maxToolCalls: cfg.maxToolCalls,
+		asm:          asm,
+		allowed:      cfg.toolAllowlist(),
+	}
+	if limits.maxSteps <= 0 {
+		limits.maxSteps = envInt("AGENT_MAX_STEPS", 5)
+	}
+	if limits.maxToolCalls <= 0 {
+		limits.maxToolCalls = envInt("AGENT_MAX_TOOL_CALLS", 8)
 	}
 
-	originalOut := strings.TrimSpace(resp.OutputText)
-
-	toolLine, hasTool := extractToolCommand(originalOut)
-
-	rv := runVerbose{FinalText: originalOut}
-
-	if hasTool {
-		parts := strings.Fields(toolLine)
-		if len(parts) >= 1 {
-			toolName := strings.TrimPrefix(parts[0], "TOOL:")
-			args := parts[1:]
-			rv.ToolRequested = toolName
-			rv.ToolArgs = args
-
-			if tc := tools.GetTool(toolName); tc != nil {
-				var toolOut string
-
-				switch tc.Type {
-				case "postgres":
-					anyArgs := make([]any, len(args))
-					for i, v := range args {
-						anyArgs[i] = v
-					}
-					toolOut, err = tools.ExecPostgres(ctx, *tc, anyArgs...)
-
-				case "postgres_embedding":
-					var query string
-					if len(args) == 0 {
-						query = userMessage
-					} else {
-						query = strings.Join(args, " ")
-					}
-					toolOut, err = tools.ExecPostgresEmbedding(ctx, cli, *tc, query)
-
-				case "script":
-					toolOut, err = tools.ExecScript(*tc, args...)
-
-				default:
-					toolOut = "Tool type não suportado ainda"
-				}
-
-				if err != nil {
-					toolOut = "Erro ao executar tool " + toolName + ": " + err.Error()
-				}
-				rv.ToolOutput = toolOut
-
-				b2 := newBuilder()
-				WithCachedContext(longPrompt)(b2)
-				if memBlock != "" {
-					WithSystemPrompt(memBlock)(b2)
-				}
-				for _, opt := range opts {
-					opt(b2)
-				}
-				WithSystemPrompt("O resultado da tool '" + toolName + "' foi:\n" + toolOut + "\nVocê DEVE usar essa informação para responder o usuário.")(b2)
-				b2.user = openai.ContentItem{Type: "input_text", Text: userMessage}
-
-				req2 := b2.req(model)
-				resp, err = cli.Respond(ctx, req2)
-				if err != nil {
-					return "", err
-				}
-				rv.FinalText = resp.OutputText
-			} else {
-				rv.ToolOutput = "Tool não encontrada: " + toolName
-			}
-		}
+	summaryThreshold := cfg.summaryThreshold
+	if summaryThreshold == 0 {
+		summaryThreshold = envInt("MEM_SUMMARY_THRESHOLD", 20)
 	}
 
+	return &runState{
+		promptPath:       promptPath,
+		model:            model,
+		respModel:        cmp.Or(cfg.model, model),
+		mem:              mem,
+		userEmb:          userEmb,
+		newReq:           newReq,
+		legacy:           cfg.legacyTools,
+		limits:           limits,
+		context:          report,
+		memDepth:         memDepth,
+		summaryThreshold: summaryThreshold,
+	}, nil
+}
+
+func (st *runState) persist(rt *Runtime, embeddingModel, sessionID, userMessage string, res *Result, resp *openai.ResponseEnvelope) error {
+	mem := st.mem
+	var userID, assistID int64
 	saveEg, saveCtx := errgroup.WithContext(context.Background())
 	saveEg.Go(func() error {
-		_, err := mem.SaveEmbeddedMessage(saveCtx, sessionID, "user", userMessage, userEmb)
+		id, err := mem.SaveEmbeddedMessage(saveCtx, sessionID, "user", userMessage, st.userEmb)
+		userID = id
 		return err
 	})
 	saveEg.Go(func() error {
 		var assistEmb []float32
-		if emb, err := cli.Embed(saveCtx, embeddingModel, rv.FinalText); err == nil {
+		if emb, err := rt.LLM.Embed(saveCtx, embeddingModel, res.Text); err == nil {
 			assistEmb = emb
 		}
-		id, err := mem.SaveEmbeddedMessage(saveCtx, sessionID, "assistant", rv.FinalText, assistEmb)
+		id, err := mem.SaveEmbeddedMessage(saveCtx, sessionID, "assistant", res.Text, assistEmb)
 		if err != nil {
 			return err
 		}
+		assistID = id
 		if resp.Raw != nil {
 			_ = mem.SaveMetadata(saveCtx, id, "response_raw", resp.Raw)
 		}
-		if rv.ToolRequested != "" {
-			_ = mem.SaveMetadata(saveCtx, id, "tool_used", rv)
+		for _, tc := range res.ToolCalls {
+			_ = mem.SaveMetadata(saveCtx, id, "tool_used", tc)
+			if tc.Denied {
+				_ = mem.SaveMetadata(saveCtx, id, "tool_denied", map[string]any{
+					"tool":    tc.Name,
+					"args":    tc.Args,
+					"prompt":  st.promptPath,
+					"route":   res.Route.chosen(),
+					"allowed": st.limits.allowed,
+				})
+			}
+		}
+		if len(res.ToolCalls) > 0 {
+			_ = mem.SaveMetadata(saveCtx, id, "tool_loop", map[string]any{
+				"steps":       res.ToolCalls[len(res.ToolCalls)-1].Step,
+				"tool_calls":  len(res.ToolCalls),
+				"stop_reason": res.StopReason,
+			})
+		}
+		if res.Route != nil {
+			_ = mem.SaveMetadata(saveCtx, id, "route", res.Route)
+		}
+
+		var facts []memory.Fact
+		for _, call := range res.ToolCalls {
+			if tc := rt.Tools.GetTool(call.Name); tc != nil {
+				facts = append(facts, extractFacts(*tc, call)...)
+			}
+		}
+		for i := range facts {
+			facts[i].SourceMessageID = id
+		}
+		if len(facts) > 0 {
+			if err := mem.SaveFacts(saveCtx, sessionID, facts); err != nil {
+				return err
+			}
+			res.Facts = facts
 		}
 		return nil
 	})
 	if err := saveEg.Wait(); err != nil {
-		return "", fmt.Errorf("persist failed: %w", err)
+		return fmt.Errorf("persist failed: %w", err)
+	}
+	res.MessageIDs = []int64{userID, assistID}
+	return nil
+}
+
+// summarize atualiza o resumo da sessão depois que o turno foi salvo. Falhas não
+// invalidam a resposta já dada; o resumo é refeito no próximo turno.
+func (st *runState) summarize(rt *Runtime, sessionID string, res *Result) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	updated, err := maybeSummarize(ctx, st.mem, res.track(rt.LLM.Respond), st.model, sessionID, st.memDepth, st.summaryThreshold)
+	if err == nil {
+		res.SummaryUpdated = updated
+	}
+}
+
+func Run(
+	ctx context.Context,
+	rt *Runtime,
+	model string,
+	embeddingModel string,
+	sessionID string,
+	promptPath string,
+	userMessage string,
+	opts ...Option,
+) (*Result, error) {
+	return run(ctx, rt, model, embeddingModel, sessionID, promptPath, userMessage, &Result{BasePrompt: promptPath}, opts...)
+}
+
+func run(
+	ctx context.Context,
+	rt *Runtime,
+	model string,
+	embeddingModel string,
+	sessionID string,
+	promptPath string,
+	userMessage string,
+	res *Result,
+	opts ...Option,
+) (*Result, error) {
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 60*time.Second)
+		defer cancel()
+	}
+
+	st, err := prepareRun(ctx, rt, model, embeddingModel, sessionID, promptPath, userMessage, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	respond := res.track(rt.LLM.Respond)
+	req := st.newReq()
+	resp, err := respond(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, calls, stop, err := runToolLoop(ctx, rt, respond, st.legacy, st.limits, st.newReq, req, resp, userMessage, nil)
+	if err != nil {
+		return nil, err
 	}
 
-	if verbose {
-		return rv.JSON(), nil
+	res.Text = strings.TrimSpace(resp.OutputText)
+	res.ToolCalls = calls
+	res.StopReason = stop
+	res.Model = st.respModel
+	res.Context = st.context
+	res.Context.addToolOutputs(st.limits.asm, calls)
+	if err := st.persist(rt, embeddingModel, sessionID, userMessage, res, resp); err != nil {
+		return nil, err
 	}
-	return rv.FinalText, nil
+	st.summarize(rt, sessionID, res)
+	return res, nil
 }
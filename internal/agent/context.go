@@ -0,0 +1,306 @@
+package agent
+
+import (
+	"sort"
+
+	"github.com/RafaelZelak/agentkit/internal/memory"
+	"github.com/RafaelZelak/agentkit/internal/tokenizer"
+)
+
+// ContextBudget limita, em tokens, cada seção do contexto enviado ao modelo.
+// Campos zerados usam o padrão; negativos desligam o limite da seção.
+type ContextBudget struct {
+	Total      int `json:"total"`
+	System     int `json:"system"`
+	Summary    int `json:"summary"`
+	Facts      int `json:"facts"`
+	Recent     int `json:"recent"`
+	Semantic   int `json:"semantic"`
+	ToolOutput int `json:"tool_output"`
+}
+
+var defaultBudget = ContextBudget{
+	Total:      16000,
+	System:     6000,
+	Summary:    800,
+	Facts:      1000,
+	Recent:     3000,
+	Semantic:   1500,
+	ToolOutput: 3000,
+}
+
+func (b ContextBudget) withDefaults() ContextBudget {
+	pick := func(v, def int) int {
+		if v == 0 {
+			return def
+		}
+		return v
+	}
+	return ContextBudget{
+		Total:      pick(b.Total, envInt("CONTEXT_MAX_TOKENS", defaultBudget.Total)),
+		System:     pick(b.System, defaultBudget.System),
+		Summary:    pick(b.Summary, defaultBudget.Summary),
+		Facts:      pick(b.Facts, defaultBudget.Facts),
+		Recent:     pick(b.Recent, defaultBudget.Recent),
+		Semantic:   pick(b.Semantic, defaultBudget.Semantic),
+		ToolOutput: pick(b.ToolOutput, defaultBudget.ToolOutput),
+	}
+}
+
+// ContextReport mostra quanto de cada seção entrou no contexto e o que foi cortado.
+type ContextReport struct {
+	Budget   int              `json:"budget"`
+	Used     int              `json:"used"`
+	Sections []ContextSection `json:"sections"`
+}
+
+// ContextSection é o uso de uma seção. Dropped conta itens descartados inteiros;
+// Truncated indica que algum texto foi encurtado.
+type ContextSection struct {
+	Name      string `json:"name"`
+	Tokens    int    `json:"tokens"`
+	Budget    int    `json:"budget"`
+	Items     int    `json:"items,omitempty"`
+	Dropped   int    `json:"dropped,omitempty"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+const truncMarker = "\n[... conteúdo truncado]"
+
+// contextParts são as peças do contexto antes e depois do corte. fixed são os
+// tokens de prompts vindos das opções (ex.: prompt da rota), que contam no
+// orçamento do sistema mas não são cortados.
+type contextParts struct {
+	system  string
+	fixed   int
+	summary *memory.Summary
+	recent  []memory.HistoryItem
+	similar []memory.HistoryItem
+	facts   []memory.Fact
+}
+
+type assembler struct {
+	tok    tokenizer.Tokenizer
+	budget ContextBudget
+}
+
+func newAssembler(tok tokenizer.Tokenizer, budget ContextBudget) assembler {
+	if tok == nil {
+		tok = tokenizer.Estimator{}
+	}
+	return assembler{tok: tok, budget: budget.withDefaults()}
+}
+
+// truncate corta text para max tokens contando o marcador. max negativo não corta.
+func (a assembler) truncate(text string, max int) (string, bool) {
+	if max < 0 || a.tok.Count(text) <= max {
+		return text, false
+	}
+	cut, _ := tokenizer.Truncate(a.tok, text, max-a.tok.Count(truncMarker))
+	if cut == "" {
+		return "", true
+	}
+	return cut + truncMarker, true
+}
+
+// itemSection é uma seção de itens descartáveis um a um, na ordem de drop. O
+// custo é o da seção renderizada como vai no bloco de memória, com cabeçalho.
+type itemSection struct {
+	rep    ContextSection
+	order  []int
+	kept   []bool
+	next   int
+	tok    tokenizer.Tokenizer
+	render func(kept []bool) string
+	cost   int // -1: recontar
+}
+
+func newItemSection(name string, budget int, tok tokenizer.Tokenizer, order []int, render func([]bool) string) *itemSection {
+	s := &itemSection{
+		rep:    ContextSection{Name: name, Budget: budget, Items: len(order)},
+		order:  order,
+		kept:   make([]bool, len(order)),
+		tok:    tok,
+		render: render,
+		cost:   -1,
+	}
+	for i := range s.kept {
+		s.kept[i] = true
+	}
+	return s
+}
+
+func (s *itemSection) tokens() int {
+	if s.cost < 0 {
+		s.cost = s.tok.Count(s.render(s.kept))
+	}
+	return s.cost
+}
+
+// dropUntil descarta itens até a seção caber em max.
+func (s *itemSection) dropUntil(max int) {
+	if max < 0 {
+		return
+	}
+	for s.tokens() > max && s.next < len(s.order) {
+		s.kept[s.order[s.next]] = false
+		s.next++
+		s.cost = -1
+		s.rep.Dropped++
+	}
+}
+
+func (s *itemSection) report() ContextSection {
+	r := s.rep
+	r.Tokens = s.tokens()
+	r.Items -= r.Dropped
+	return r
+}
+
+func (a assembler) historySection(name, header string, items []memory.HistoryItem, budget int, oldestFirst bool) ([]memory.HistoryItem, *itemSection) {
+	out := make([]memory.HistoryItem, len(items))
+	copy(out, items)
+	order := make([]int, len(out))
+	truncated := false
+	for i := range out {
+		// nenhum item sozinho ocupa mais que metade da seção
+		if budget > 0 {
+			var cut bool
+			out[i].Text, cut = a.truncate(out[i].Text, budget/2)
+			truncated = truncated || cut
+		}
+		if oldestFirst {
+			order[i] = i
+		} else {
+			order[i] = len(out) - 1 - i
+		}
+	}
+	s := newItemSection(name, budget, a.tok, order, func(kept []bool) string {
+		return historyBlock(header, keep(out, kept))
+	})
+	s.rep.Truncated = truncated
+	return out, s
+}
+
+func (a assembler) factSection(facts []memory.Fact, budget int) *itemSection {
+	order := make([]int, len(facts))
+	for i := range facts {
+		order[i] = i
+	}
+	// descarta primeiro os fatos atualizados há mais tempo
+	sort.SliceStable(order, func(x, y int) bool {
+		return facts[order[x]].UpdatedAt.Before(facts[order[y]].UpdatedAt)
+	})
+	return newItemSection("facts", budget, a.tok, order, func(kept []bool) string {
+		return factsBlock(keep(facts, kept))
+	})
+}
+
+func keep[T any](items []T, kept []bool) []T {
+	var out []T
+	for i, it := range items {
+		if kept[i] {
+			out = append(out, it)
+		}
+	}
+	return out
+}
+
+// fit aplica os orçamentos por seção e depois o total, descartando na ordem de
+// prioridade: memória semântica, memória curta (mais antigas primeiro), fatos
+// (mais antigos primeiro), resumo e, por último, o fim do prompt de sistema.
+func (a assembler) fit(p contextParts) (contextParts, *ContextReport) {
+	b := a.budget
+	out := contextParts{fixed: p.fixed}
+
+	sysRep := ContextSection{Name: "system", Budget: b.System}
+	sysLimit := -1
+	if b.System > 0 {
+		sysLimit = max(b.System-p.fixed, 0)
+	}
+	out.system, sysRep.Truncated = a.truncate(p.system, sysLimit)
+
+	sumRep := ContextSection{Name: "summary", Budget: b.Summary}
+	var summaryText string
+	if p.summary != nil && p.summary.Text != "" {
+		sumRep.Items = 1
+		// o orçamento inclui o cabeçalho e a linha em branco da seção
+		limit := b.Summary
+		if limit > 0 {
+			limit = max(limit-a.tok.Count(summaryBlock(" ")), 0)
+		}
+		summaryText, sumRep.Truncated = a.truncate(p.summary.Text, limit)
+	}
+
+	recent, recentSec := a.historySection("recent", recentHeader, p.recent, b.Recent, true)
+	similar, semSec := a.historySection("semantic", semanticHeader, p.similar, b.Semantic, false)
+	factSec := a.factSection(p.facts, b.Facts)
+	recentSec.dropUntil(b.Recent)
+	semSec.dropUntil(b.Semantic)
+	factSec.dropUntil(b.Facts)
+
+	used := func() int {
+		return a.tok.Count(out.system) + p.fixed + a.tok.Count(summaryBlock(summaryText)) +
+			recentSec.tokens() + semSec.tokens() + factSec.tokens()
+	}
+	if b.Total > 0 {
+		for _, s := range []*itemSection{semSec, recentSec, factSec} {
+			if over := used() - b.Total; over > 0 {
+				s.dropUntil(max(s.tokens()-over, 0))
+			}
+		}
+		if used() > b.Total && summaryText != "" {
+			summaryText = ""
+			sumRep.Dropped = 1
+			sumRep.Items = 0
+		}
+		if over := used() - b.Total; over > 0 {
+			var cut bool
+			out.system, cut = a.truncate(out.system, max(a.tok.Count(out.system)-over, 0))
+			sysRep.Truncated = sysRep.Truncated || cut
+		}
+	}
+
+	if summaryText != "" {
+		sum := *p.summary
+		sum.Text = summaryText
+		out.summary = &sum
+	}
+	out.recent = keep(recent, recentSec.kept)
+	out.similar = keep(similar, semSec.kept)
+	out.facts = keep(p.facts, factSec.kept)
+
+	sysRep.Tokens = a.tok.Count(out.system) + p.fixed
+	sumRep.Tokens = a.tok.Count(summaryBlock(summaryText))
+	rep := &ContextReport{
+		Budget: b.Total,
+		Used:   used(),
+		Sections: []ContextSection{
+			sysRep,
+			sumRep,
+			factSec.report(),
+			recentSec.report(),
+			semSec.report(),
+		},
+	}
+	return out, rep
+}
+
+// truncateToolOutput aplica o orçamento de saída por chamada de tool.
+func (a assembler) truncateToolOutput(out string) (string, bool) {
+	return a.truncate(out, a.budget.ToolOutput)
+}
+
+// addToolOutputs registra no relatório a seção de saídas de tools do loop.
+func (r *ContextReport) addToolOutputs(a assembler, calls []ToolCall) {
+	if r == nil || len(calls) == 0 {
+		return
+	}
+	sec := ContextSection{Name: "tool_output", Budget: a.budget.ToolOutput, Items: len(calls)}
+	for _, c := range calls {
+		sec.Tokens += a.tok.Count(c.Output)
+		sec.Truncated = sec.Truncated || c.Truncated
+	}
+	r.Used += sec.Tokens
+	r.Sections = append(r.Sections, sec)
+}
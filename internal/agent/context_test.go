@@ -0,0 +1,155 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RafaelZelak/agentkit/internal/memory"
+	"github.com/RafaelZelak/agentkit/internal/tokenizer"
+)
+
+func words(n int) string {
+	return strings.TrimSpace(strings.Repeat("word ", n))
+}
+
+func TestAssemblerFit(t *testing.T) {
+	tok := tokenizer.Estimator{}
+	now := time.Now()
+
+	tests := []struct {
+		name        string
+		budget      ContextBudget
+		parts       contextParts
+		wantSystem  bool // system inteiro
+		wantRecent  int
+		wantSimilar int
+		wantFacts   int
+		wantSummary bool
+	}{
+		{
+			name:   "tudo cabe",
+			budget: ContextBudget{Total: 1000},
+			parts: contextParts{
+				system:  words(10),
+				summary: &memory.Summary{Text: words(5)},
+				recent:  []memory.HistoryItem{{Role: "user", Text: words(5)}},
+				similar: []memory.HistoryItem{{Role: "user", Text: words(5)}},
+				facts:   []memory.Fact{{Key: "k", Field: "f", Value: "v", UpdatedAt: now}},
+			},
+			wantSystem: true, wantRecent: 1, wantSimilar: 1, wantFacts: 1, wantSummary: true,
+		},
+		{
+			// o excesso é maior que a seção semântica inteira: ela e as outras
+			// seções saem antes de o prompt de sistema ser cortado
+			name:   "excesso maior que a seção esvazia antes do sistema",
+			budget: ContextBudget{Total: 100},
+			parts: contextParts{
+				system:  words(75),
+				summary: &memory.Summary{Text: words(5)},
+				recent:  []memory.HistoryItem{{Role: "user", Text: words(5)}},
+				similar: []memory.HistoryItem{{Role: "user", Text: words(5)}},
+				facts:   []memory.Fact{{Key: "k", Field: "f", Value: "v", UpdatedAt: now}},
+			},
+			wantSystem: false,
+		},
+		{
+			name:   "semântica sai primeiro",
+			budget: ContextBudget{Total: 60},
+			parts: contextParts{
+				system:  words(10),
+				recent:  []memory.HistoryItem{{Role: "user", Text: words(10)}},
+				similar: []memory.HistoryItem{{Role: "user", Text: words(10)}},
+			},
+			wantSystem: true, wantRecent: 1, wantSimilar: 0,
+		},
+		{
+			name:   "memória curta descarta as mais antigas",
+			budget: ContextBudget{Total: 1000, Recent: 50},
+			parts: contextParts{
+				system: words(5),
+				recent: []memory.HistoryItem{
+					{ID: 1, Role: "user", Text: words(10)},
+					{ID: 2, Role: "assistant", Text: words(10)},
+				},
+			},
+			wantSystem: true, wantRecent: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAssembler(tok, tt.budget)
+			out, rep := a.fit(tt.parts)
+
+			if got := out.system == tt.parts.system; got != tt.wantSystem {
+				t.Errorf("system inteiro = %v, quer %v (%d tokens)", got, tt.wantSystem, tok.Count(out.system))
+			}
+			if len(out.recent) != tt.wantRecent {
+				t.Errorf("recent = %d, quer %d", len(out.recent), tt.wantRecent)
+			}
+			if len(out.similar) != tt.wantSimilar {
+				t.Errorf("similar = %d, quer %d", len(out.similar), tt.wantSimilar)
+			}
+			if len(out.facts) != tt.wantFacts {
+				t.Errorf("facts = %d, quer %d", len(out.facts), tt.wantFacts)
+			}
+			if (out.summary != nil) != tt.wantSummary {
+				t.Errorf("summary = %v, quer %v", out.summary != nil, tt.wantSummary)
+			}
+			if rep.Used > a.budget.Total {
+				t.Errorf("used = %d, acima do total %d", rep.Used, a.budget.Total)
+			}
+		})
+	}
+}
+
+func TestAssemblerFitKeepsNewestRecent(t *testing.T) {
+	a := newAssembler(tokenizer.Estimator{}, ContextBudget{Total: 1000, Recent: 50})
+	out, _ := a.fit(contextParts{recent: []memory.HistoryItem{
+		{ID: 1, Role: "user", Text: words(10)},
+		{ID: 2, Role: "assistant", Text: words(10)},
+	}})
+	if len(out.recent) != 1 || out.recent[0].ID != 2 {
+		t.Fatalf("recent = %+v, quer só a mensagem 2", out.recent)
+	}
+}
+
+// O relatório conta exatamente o texto que vai para o modelo: prompt de sistema
+// e bloco de memória com cabeçalhos.
+func TestAssemblerReportMatchesRenderedText(t *testing.T) {
+	tok := tokenizer.Estimator{}
+	now := time.Now()
+	parts := contextParts{
+		system:  words(20),
+		summary: &memory.Summary{Text: words(15)},
+		recent: []memory.HistoryItem{
+			{ID: 1, Role: "user", Text: words(8)},
+			{ID: 2, Role: "assistant", Text: words(12)},
+		},
+		similar: []memory.HistoryItem{{Role: "user", Text: words(6)}},
+		facts: []memory.Fact{
+			{Category: "cliente", Key: "123", Field: "nome", Value: "Ana", UpdatedAt: now},
+			{Category: "cliente", Key: "123", Field: "plano", Value: "ouro", UpdatedAt: now},
+			{Category: "boleto", Key: "9", Field: "status", Value: "pago", UpdatedAt: now.Add(-time.Hour)},
+		},
+	}
+	for _, total := range []int{1000, 150, 100, 60} {
+		a := newAssembler(tok, ContextBudget{Total: total})
+		out, rep := a.fit(parts)
+		rendered := tok.Count(out.system) + tok.Count(buildMemBlock(out.summary, out.recent, out.similar, out.facts))
+		if rep.Used != rendered {
+			t.Errorf("total %d: used = %d, texto renderizado = %d", total, rep.Used, rendered)
+		}
+		if rep.Used > total {
+			t.Errorf("total %d: used = %d acima do orçamento", total, rep.Used)
+		}
+		sum := 0
+		for _, s := range rep.Sections {
+			sum += s.Tokens
+		}
+		if sum != rep.Used {
+			t.Errorf("total %d: soma das seções %d ≠ used %d", total, sum, rep.Used)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package agent
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/RafaelZelak/agentkit/internal/memory"
+	"github.com/RafaelZelak/agentkit/internal/tools"
+)
+
+var kvRe = regexp.MustCompile(`(?:^|\s)([A-Za-z_][A-Za-z0-9_]*)=`)
+
+// parseOutputRows interpreta a saída de uma tool como registros: um objeto JSON
+// por linha ou "coluna=valor coluna=valor" (formato do ExecPostgres).
+func parseOutputRows(out string) []map[string]string {
+	var rows []map[string]string
+	for _, ln := range strings.Split(out, "\n") {
+		ln = strings.TrimSpace(ln)
+		if ln == "" {
+			continue
+		}
+		if strings.HasPrefix(ln, "{") {
+			var obj map[string]any
+			if err := json.Unmarshal([]byte(ln), &obj); err == nil {
+				row := make(map[string]string, len(obj))
+				for k, v := range obj {
+					row[k] = factValue(v)
+				}
+				rows = append(rows, row)
+				continue
+			}
+		}
+		locs := kvRe.FindAllStringSubmatchIndex(ln, -1)
+		if len(locs) == 0 {
+			continue
+		}
+		row := make(map[string]string, len(locs))
+		for i, loc := range locs {
+			end := len(ln)
+			if i+1 < len(locs) {
+				end = locs[i+1][0]
+			}
+			// o formato kv escreve NULL para valores nulos
+			if v := strings.TrimSpace(ln[loc[1]:end]); v != "NULL" {
+				row[ln[loc[2]:loc[3]]] = v
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func factValue(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	default:
+		js, _ := json.Marshal(x)
+		return string(js)
+	}
+}
+
+// extractFacts aplica as regras facts: do tools.yml à saída de uma chamada.
+func extractFacts(tc tools.ToolConfig, call ToolCall) []memory.Fact {
+	if len(tc.Facts) == 0 || call.Failed {
+		return nil
+	}
+	rows := parseOutputRows(call.Output)
+	var out []memory.Fact
+	for _, fc := range tc.Facts {
+		category := fc.Category
+		if category == "" {
+			category = tc.Name
+		}
+		for _, row := range rows {
+			key := ""
+			if fc.KeyColumn != "" {
+				key = row[fc.KeyColumn]
+			} else if fc.KeyArg != "" {
+				key = tc.ArgByName(call.Args, fc.KeyArg)
+			}
+			key = strings.TrimSpace(key)
+			if key == "" {
+				continue
+			}
+			for _, field := range fc.Fields {
+				v, ok := row[field]
+				if !ok || v == "" {
+					continue
+				}
+				out = append(out, memory.Fact{
+					Category: category,
+					Key:      key,
+					Field:    field,
+					Value:    v,
+				})
+			}
+		}
+	}
+	return out
+}
+
+// writeFacts escreve os fatos agrupados por categoria e chave. Espera a ordem
+// devolvida por LoadFacts (categoria, chave, campo).
+func writeFacts(sb *strings.Builder, facts []memory.Fact) {
+	category, key := "", ""
+	for i, f := range facts {
+		if i == 0 || f.Category != category {
+			if i > 0 {
+				sb.WriteByte('\n')
+			}
+			category, key = f.Category, ""
+			sb.WriteString("[")
+			sb.WriteString(category)
+			sb.WriteString("]")
+		}
+		if f.Key != key {
+			key = f.Key
+			sb.WriteString("\n- ")
+			sb.WriteString(key)
+			sb.WriteString(": ")
+		} else {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(f.Field)
+		sb.WriteByte('=')
+		sb.WriteString(f.Value)
+	}
+	if len(facts) > 0 {
+		sb.WriteByte('\n')
+	}
+}
@@ -0,0 +1,62 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/RafaelZelak/agentkit/internal/tools"
+)
+
+func TestExtractFacts(t *testing.T) {
+	tc := tools.ToolConfig{
+		Name:          "clientes",
+		QueryTemplate: "SELECT * FROM clientes WHERE cpf = $1",
+		Params:        []tools.ParamConfig{{Name: "cpf"}},
+		Facts:         []tools.FactConfig{{KeyArg: "cpf", Fields: []string{"nome", "plano"}}},
+	}
+	tests := []struct {
+		name   string
+		output string
+		want   map[string]string
+	}{
+		{
+			name:   "kv",
+			output: "nome=Ana Souza plano=ouro\n",
+			want:   map[string]string{"nome": "Ana Souza", "plano": "ouro"},
+		},
+		{
+			name:   "kv com NULL",
+			output: "nome=Ana plano=NULL\n",
+			want:   map[string]string{"nome": "Ana"},
+		},
+		{
+			name:   "jsonl com null",
+			output: `{"nome":"Ana","plano":null}` + "\n",
+			want:   map[string]string{"nome": "Ana"},
+		},
+		{
+			name:   "sem registros",
+			output: "Nenhum resultado encontrado.",
+			want:   map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			facts := extractFacts(tc, ToolCall{Args: []string{"123"}, Output: tt.output})
+			got := map[string]string{}
+			for _, f := range facts {
+				if f.Key != "123" || f.Category != "clientes" {
+					t.Errorf("fato %+v com chave ou categoria errada", f)
+				}
+				got[f.Field] = f.Value
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, quer %v", got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("%s = %q, quer %q", k, got[k], v)
+				}
+			}
+		})
+	}
+}
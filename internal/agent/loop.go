@@ -0,0 +1,199 @@
+package agent
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/RafaelZelak/agentkit/internal/openai"
+)
+
+const (
+	stopFinal        = "final"
+	stopMaxSteps     = "max_steps"
+	stopMaxToolCalls = "max_tool_calls"
+	stopRepeated     = "repeated_call"
+	stopClarify      = "clarify"
+)
+
+const limitMsg = "Limite de chamadas de tools atingido. Responda ao usuário agora com as informações que você já tem, sem chamar novas tools."
+
+type respondFunc func(context.Context, *openai.ResponsesRequest) (*openai.ResponseEnvelope, error)
+
+type loopLimits struct {
+	maxSteps     int
+	maxToolCalls int
+	asm          assembler
+	allowed      []string // nil libera todas
+}
+
+type pendingCall struct {
+	name string
+	args []string
+	fc   openai.FunctionCall
+}
+
+func (p pendingCall) key() string {
+	if p.fc.CallID != "" {
+		return p.name + "\x00" + p.fc.Arguments
+	}
+	return p.name + "\x00" + strings.Join(p.args, "\x00")
+}
+
+func pendingCalls(resp *openai.ResponseEnvelope, legacy bool) []pendingCall {
+	if !legacy {
+		out := make([]pendingCall, 0, len(resp.ToolCalls))
+		for _, fc := range resp.ToolCalls {
+			out = append(out, pendingCall{name: fc.Name, fc: fc})
+		}
+		return out
+	}
+	toolLine, ok := extractToolCommand(strings.TrimSpace(resp.OutputText))
+	if !ok {
+		return nil
+	}
+	parts := strings.Fields(toolLine)
+	if len(parts) == 0 {
+		return nil
+	}
+	return []pendingCall{{name: strings.TrimPrefix(parts[0], "TOOL:"), args: parts[1:]}}
+}
+
+// deniedMsg é devolvido ao modelo no lugar da saída de uma tool fora da lista
+// permitida para o prompt.
+func deniedMsg(name string, allowed []string) string {
+	msg := "Tool não permitida neste atendimento: " + name + ". Ela não foi executada."
+	if len(allowed) == 0 {
+		return msg + " Nenhuma tool está disponível; responda sem usar tools."
+	}
+	return msg + " Tools permitidas: " + strings.Join(allowed, ", ") + "."
+}
+
+func runCall(ctx context.Context, rt *Runtime, p pendingCall, userMessage string) (args []string, out string, failed bool) {
+	tc := rt.Tools.GetTool(p.name)
+	if tc == nil {
+		return p.args, "Tool não encontrada: " + p.name, true
+	}
+	var err error
+	if p.fc.CallID != "" {
+		args, err = tc.DecodeArgs(p.fc.Arguments)
+	} else {
+		args, err = tc.CheckArgs(p.args)
+	}
+	if err != nil {
+		return p.args, "Erro ao executar tool " + p.name + ": " + err.Error(), true
+	}
+	out, err = execTool(ctx, rt, *tc, args, userMessage)
+	if err != nil {
+		return args, "Erro ao executar tool " + p.name + ": " + err.Error(), true
+	}
+	if out == "" {
+		out = "(vazio)"
+	}
+	return args, out, false
+}
+
+// runToolLoop executa as tools pedidas pelo modelo e devolve os resultados até
+// que ele produza uma resposta final ou o orçamento de passos/chamadas acabe.
+// newReq monta um request do zero com opções extras (usado no modo legacy, em que
+// os resultados vão como prompts de sistema). onStep, se não for nil, é chamado
+// após cada tool executada.
+func runToolLoop(
+	ctx context.Context,
+	rt *Runtime,
+	respond respondFunc,
+	legacy bool,
+	limits loopLimits,
+	newReq func(extra ...Option) *openai.ResponsesRequest,
+	req *openai.ResponsesRequest,
+	resp *openai.ResponseEnvelope,
+	userMessage string,
+	onStep func(ToolCall),
+) (*openai.ResponseEnvelope, []ToolCall, string, error) {
+	var (
+		steps     []ToolCall
+		legacyCtx []Option
+		calls     int
+		err       error
+	)
+	seen := map[string]bool{}
+
+	for step := 1; ; step++ {
+		pending := pendingCalls(resp, legacy)
+		if len(pending) == 0 {
+			return resp, steps, stopFinal, nil
+		}
+
+		stop := ""
+		switch {
+		case step > limits.maxSteps:
+			stop = stopMaxSteps
+		case calls >= limits.maxToolCalls:
+			stop = stopMaxToolCalls
+		case allSeen(pending, seen):
+			stop = stopRepeated
+		}
+		if stop != "" {
+			if legacy {
+				req = newReq(append(legacyCtx, WithSystemPrompt(limitMsg))...)
+			} else {
+				for _, p := range pending {
+					req.Input = append(req.Input, p.fc.Item(), openai.FunctionCallOutput(p.fc.CallID, limitMsg))
+				}
+				req.ToolChoice = "none"
+			}
+			resp, err = respond(ctx, req)
+			return resp, steps, stop, err
+		}
+
+		for _, p := range pending {
+			st := ToolCall{Step: step, Name: p.name, Args: p.args}
+			if calls >= limits.maxToolCalls {
+				st.Output = limitMsg
+				st.Failed = true
+			} else if limits.allowed != nil && !slices.Contains(limits.allowed, p.name) {
+				calls++
+				seen[p.key()] = true
+				st.Output = deniedMsg(p.name, limits.allowed)
+				st.Failed = true
+				st.Denied = true
+				rt.logger().Warn("tool fora da lista permitida", "tool", p.name, "allowed", limits.allowed)
+			} else {
+				calls++
+				seen[p.key()] = true
+				start := time.Now()
+				st.Args, st.Output, st.Failed = runCall(ctx, rt, p, userMessage)
+				st.Duration = time.Since(start)
+				st.Output, st.Truncated = limits.asm.truncateToolOutput(st.Output)
+			}
+			steps = append(steps, st)
+			if onStep != nil {
+				onStep(st)
+			}
+
+			if legacy {
+				legacyCtx = append(legacyCtx, WithSystemPrompt("O resultado da tool '"+p.name+"' foi:\n"+st.Output+"\nVocê DEVE usar essa informação para responder o usuário."))
+			} else {
+				req.Input = append(req.Input, p.fc.Item(), openai.FunctionCallOutput(p.fc.CallID, st.Output))
+			}
+		}
+
+		if legacy {
+			req = newReq(legacyCtx...)
+		}
+		resp, err = respond(ctx, req)
+		if err != nil {
+			return nil, steps, "", err
+		}
+	}
+}
+
+func allSeen(pending []pendingCall, seen map[string]bool) bool {
+	for _, p := range pending {
+		if !seen[p.key()] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,36 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/RafaelZelak/agentkit/internal/openai"
+)
+
+func TestToolLoopLogsDeniedCall(t *testing.T) {
+	var logs bytes.Buffer
+	rt := &Runtime{Logger: slog.New(slog.NewTextHandler(&logs, nil))}
+	respond := func(context.Context, *openai.ResponsesRequest) (*openai.ResponseEnvelope, error) {
+		return &openai.ResponseEnvelope{OutputText: "ok"}, nil
+	}
+	first := &openai.ResponseEnvelope{ToolCalls: []openai.FunctionCall{
+		{CallID: "c1", Name: "apaga_tudo", Arguments: "{}"},
+	}}
+	limits := loopLimits{maxSteps: 3, maxToolCalls: 3, allowed: []string{"consulta"}}
+
+	_, calls, stop, err := runToolLoop(context.Background(), rt, respond, false, limits,
+		func(...Option) *openai.ResponsesRequest { return &openai.ResponsesRequest{} },
+		&openai.ResponsesRequest{}, first, "oi", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stop != stopFinal || len(calls) != 1 || !calls[0].Denied {
+		t.Fatalf("stop = %q, calls = %+v", stop, calls)
+	}
+	if !strings.Contains(logs.String(), "tool=apaga_tudo") {
+		t.Errorf("recusa não registrada no log: %q", logs.String())
+	}
+}
@@ -42,3 +42,147 @@ func WithCachedContext(text string) Option {
 		}
 	}
 }
+
+// WithLegacyToolProtocol desliga o function calling nativo e volta a interpretar
+// linhas "TOOL:<nome> <args>" na resposta do modelo.
+func WithLegacyToolProtocol() Option {
+	return func(b *builder) {
+		b.legacyTools = true
+	}
+}
+
+// WithMaxSteps limita quantas rodadas de tools o modelo pode encadear antes da resposta final.
+func WithMaxSteps(n int) Option {
+	return func(b *builder) {
+		b.maxSteps = n
+	}
+}
+
+// WithMaxToolCalls limita o total de tools executadas em um Run.
+func WithMaxToolCalls(n int) Option {
+	return func(b *builder) {
+		b.maxToolCalls = n
+	}
+}
+
+// WithSummaryThreshold define quantas mensagens fora da memória curta disparam a
+// atualização do resumo da sessão. Valores negativos desligam o resumo.
+func WithSummaryThreshold(n int) Option {
+	return func(b *builder) {
+		b.summaryThreshold = n
+	}
+}
+
+// WithContextBudget define os limites de tokens por seção do contexto.
+func WithContextBudget(budget ContextBudget) Option {
+	return func(b *builder) {
+		b.budget = budget
+	}
+}
+
+// WithRouterMode escolhe como RouteAndRun decide a rota: RouterLLM (padrão) pede
+// ao modelo; RouterEmbedding compara o embedding da mensagem com o das rotas.
+func WithRouterMode(mode string) Option {
+	return func(b *builder) {
+		b.routerMode = mode
+	}
+}
+
+// WithRouterTieBreak faz o modo embedding consultar o LLM entre os dois melhores
+// candidatos quando a diferença de score entre eles é menor que margin.
+func WithRouterTieBreak(margin float64) Option {
+	return func(b *builder) {
+		b.routerTieMargin = margin
+	}
+}
+
+// Ações quando a confiança do router fica abaixo do limite.
+const (
+	LowConfidenceFallback = "fallback"
+	LowConfidenceClarify  = "clarify"
+)
+
+// WithRouterThreshold define a confiança mínima do router. Abaixo dela, onLow
+// decide entre usar a rota de fallback (LowConfidenceFallback, padrão) ou
+// responder com uma pergunta de esclarecimento (LowConfidenceClarify).
+func WithRouterThreshold(min float64, onLow string) Option {
+	return func(b *builder) {
+		b.routerThreshold = min
+		b.routerOnLow = onLow
+	}
+}
+
+// WithStickyRouting mantém a rota atual da sessão e só troca quando o router
+// escolhe outra com confiança >= minSwitch. Zero desliga.
+func WithStickyRouting(minSwitch float64) Option {
+	return func(b *builder) {
+		b.routerSwitch = minSwitch
+	}
+}
+
+// WithModel troca o modelo usado nas respostas (não afeta router nem resumo).
+func WithModel(model string) Option {
+	return func(b *builder) {
+		b.model = model
+	}
+}
+
+func WithTemperature(t float64) Option {
+	return func(b *builder) {
+		b.temperature = &t
+	}
+}
+
+func WithMaxOutputTokens(n int) Option {
+	return func(b *builder) {
+		b.maxOutputTokens = n
+	}
+}
+
+// WithAllowedTools restringe as tools que o modelo pode usar às informadas:
+// só elas são oferecidas, e chamadas a outras são recusadas. Sem nomes, nenhuma
+// tool é permitida. Tools fora da lista tools: do prompt base continuam
+// proibidas.
+func WithAllowedTools(names ...string) Option {
+	return func(b *builder) {
+		b.allowedTools = append([]string{}, names...)
+	}
+}
+
+// O que RouteAndRun faz quando não chega a uma rota.
+const (
+	OnNoRouteFallback = "fallback"
+	OnNoRouteError    = "error"
+)
+
+// WithRouterFallback define o prompt usado quando o router não chega a uma rota
+// (padrão geral.md). O front matter do router.md tem prioridade.
+func WithRouterFallback(prompt string) Option {
+	return func(b *builder) {
+		b.routerFallback = prompt
+	}
+}
+
+// WithRouterRetries define quantas vezes o router é perguntado de novo após uma
+// resposta inválida (padrão 1; negativo não repete).
+func WithRouterRetries(n int) Option {
+	return func(b *builder) {
+		b.routerRetries = n
+	}
+}
+
+// WithRouterOnNoRoute escolhe entre usar o fallback (OnNoRouteFallback, padrão)
+// ou devolver *NoRouteError (OnNoRouteError) quando não há rota.
+func WithRouterOnNoRoute(action string) Option {
+	return func(b *builder) {
+		b.routerOnNoRoute = action
+	}
+}
+
+// WithStrictToolAllowlist faz prompts sem a lista tools: no front matter (ou
+// WithAllowedTools) não poderem usar nenhuma tool.
+func WithStrictToolAllowlist() Option {
+	return func(b *builder) {
+		b.strictTools = true
+	}
+}
@@ -0,0 +1,156 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// promptFile é um arquivo de prompt .md com front matter YAML opcional:
+//
+//	---
+//	description: Dúvidas sobre boletos, pagamentos e 2ª via
+//	examples:
+//	  - quero a segunda via do boleto
+//	model: gpt-4.1-mini
+//	temperature: 0.2
+//	max_tokens: 800
+//	tools: [consulta_boleto]
+//	---
+//	Você é o atendente financeiro...
+//
+// Uma categoria é um subdiretório com router.md: Name termina em "/", Path é o
+// router.md e Meta/Body vêm dele.
+type promptFile struct {
+	Name     string
+	Path     string
+	Meta     promptMeta
+	Body     string
+	Category bool
+}
+
+// promptMeta é o front matter. Em uma rota, model, temperature, max_tokens e
+// tools substituem os do prompt base; tools ausente libera todas as tools do
+// catálogo e "tools: []" não libera nenhuma.
+type promptMeta struct {
+	Description string   `yaml:"description"`
+	Examples    []string `yaml:"examples"`
+
+	Model       string   `yaml:"model"`
+	Temperature *float64 `yaml:"temperature"`
+	MaxTokens   int      `yaml:"max_tokens"`
+	Tools       []string `yaml:"tools"`
+
+	// Só no router.md: prompt de fallback, quantas vezes repetir a pergunta
+	// após uma resposta inválida e o que fazer sem rota ("fallback" ou "error").
+	Fallback  string `yaml:"fallback"`
+	Retries   *int   `yaml:"retries"`
+	OnNoRoute string `yaml:"on_no_route"`
+}
+
+func (m promptMeta) options() []Option {
+	var opts []Option
+	if m.Model != "" {
+		opts = append(opts, WithModel(m.Model))
+	}
+	if m.Temperature != nil {
+		opts = append(opts, WithTemperature(*m.Temperature))
+	}
+	if m.MaxTokens > 0 {
+		opts = append(opts, WithMaxOutputTokens(m.MaxTokens))
+	}
+	if m.Tools != nil {
+		opts = append(opts, WithAllowedTools(m.Tools...))
+	}
+	return opts
+}
+
+// baseOptions são as opções do front matter do prompt base. A lista tools: dele
+// vira um teto: rotas e WithAllowedTools só podem estreitá-la.
+func (m promptMeta) baseOptions() []Option {
+	ceiling := m.Tools
+	m.Tools = nil
+	opts := m.options()
+	if ceiling != nil {
+		opts = append(opts, func(b *builder) {
+			b.toolCeiling = append([]string{}, ceiling...)
+		})
+	}
+	return opts
+}
+
+func loadPrompt(path string) (*promptFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p := &promptFile{Name: filepath.Base(path), Path: path, Body: string(data)}
+	head, body, ok := splitFrontMatter(p.Body)
+	if !ok {
+		return p, nil
+	}
+	if err := yaml.Unmarshal([]byte(head), &p.Meta); err != nil {
+		return nil, fmt.Errorf("front matter %s: %w", path, err)
+	}
+	p.Body = body
+	return p, nil
+}
+
+// splitFrontMatter separa o bloco entre linhas "---" no início do arquivo.
+func splitFrontMatter(s string) (head, body string, ok bool) {
+	rest, found := strings.CutPrefix(strings.TrimPrefix(s, "\ufeff"), "---")
+	if !found {
+		return "", s, false
+	}
+	nl := strings.IndexByte(rest, '\n')
+	if nl < 0 || strings.TrimSpace(rest[:nl]) != "" {
+		return "", s, false
+	}
+	rest = rest[nl+1:]
+	for off := 0; off < len(rest); {
+		end := strings.IndexByte(rest[off:], '\n')
+		line := rest[off:]
+		if end >= 0 {
+			line = rest[off : off+end]
+		}
+		if strings.TrimRight(line, " \t\r") == "---" {
+			body = ""
+			if end >= 0 {
+				body = rest[off+end+1:]
+			}
+			return rest[:off], body, true
+		}
+		if end < 0 {
+			break
+		}
+		off += end + 1
+	}
+	return "", s, false
+}
+
+// routeTexts são os textos que representam a rota na busca por embedding:
+// description e examples do front matter ou, sem eles, o início do corpo.
+func (p *promptFile) routeTexts() []string {
+	var out []string
+	if d := strings.TrimSpace(p.Meta.Description); d != "" {
+		out = append(out, d)
+	}
+	for _, ex := range p.Meta.Examples {
+		if ex = strings.TrimSpace(ex); ex != "" {
+			out = append(out, ex)
+		}
+	}
+	if len(out) == 0 {
+		body := []rune(strings.TrimSpace(p.Body))
+		if len(body) > 2000 {
+			body = body[:2000]
+		}
+		if len(body) > 0 {
+			out = append(out, string(body))
+		}
+	}
+	return out
+}
@@ -0,0 +1,104 @@
+package agent
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitFrontMatter(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       string
+		wantHead string
+		wantBody string
+		wantOK   bool
+	}{
+		{
+			name:     "com front matter",
+			in:       "---\ndescription: Boletos\n---\nVocê é o suporte.\n",
+			wantHead: "description: Boletos\n",
+			wantBody: "Você é o suporte.\n",
+			wantOK:   true,
+		},
+		{
+			name:     "crlf e BOM",
+			in:       "\ufeff---\r\nmodel: gpt\r\n---\r\ncorpo",
+			wantHead: "model: gpt\r\n",
+			wantBody: "corpo",
+			wantOK:   true,
+		},
+		{
+			name:     "front matter vazio sem corpo",
+			in:       "---\n---",
+			wantHead: "",
+			wantBody: "",
+			wantOK:   true,
+		},
+		{
+			name:     "sem front matter",
+			in:       "Você é o suporte.",
+			wantBody: "Você é o suporte.",
+		},
+		{
+			name:     "--- sem fechamento",
+			in:       "---\ndescription: x\ncorpo",
+			wantBody: "---\ndescription: x\ncorpo",
+		},
+		{
+			name:     "texto depois do ---",
+			in:       "--- título\ncorpo",
+			wantBody: "--- título\ncorpo",
+		},
+		{
+			name:     "linha com --- dentro do valor não fecha",
+			in:       "---\ndescription: a --- b\n---\ncorpo",
+			wantHead: "description: a --- b\n",
+			wantBody: "corpo",
+			wantOK:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			head, body, ok := splitFrontMatter(tt.in)
+			if head != tt.wantHead || body != tt.wantBody || ok != tt.wantOK {
+				t.Errorf("got (%q, %q, %v), quer (%q, %q, %v)", head, body, ok, tt.wantHead, tt.wantBody, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestToolAllowlistComposition(t *testing.T) {
+	tests := []struct {
+		name   string
+		base   []string
+		route  []string
+		extra  []Option
+		strict bool
+		want   []string // nil libera todas
+	}{
+		{name: "sem listas", want: nil},
+		{name: "só base", base: []string{"a", "b"}, want: []string{"a", "b"}},
+		{name: "só rota", route: []string{"c"}, want: []string{"c"}},
+		{name: "rota estreita a base", base: []string{"a", "b"}, route: []string{"b"}, want: []string{"b"}},
+		{name: "rota não libera o que a base proíbe", base: []string{"a"}, route: []string{"a", "x"}, want: []string{"a"}},
+		{name: "listas sem interseção", base: []string{"a"}, route: []string{"x"}, want: []string{}},
+		{name: "base vazia proíbe tudo", base: []string{}, route: []string{"a"}, want: []string{}},
+		{name: "WithAllowedTools também fica sob a base", base: []string{"a", "b"}, extra: []Option{WithAllowedTools("b", "z")}, want: []string{"b"}},
+		{name: "estrito sem listas", strict: true, want: []string{}},
+		{name: "estrito com base", base: []string{"a"}, strict: true, want: []string{"a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := promptMeta{Tools: tt.base}.baseOptions()
+			if tt.strict {
+				opts = append(opts, WithStrictToolAllowlist())
+			}
+			opts = append(opts, tt.extra...)
+			opts = append(opts, promptMeta{Tools: tt.route}.options()...)
+			got := applyOptions(opts).toolAllowlist()
+			if (got == nil) != (tt.want == nil) || !slices.Equal(got, tt.want) {
+				t.Errorf("allowlist = %#v, quer %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/RafaelZelak/agentkit/internal/memory"
+	"github.com/RafaelZelak/agentkit/internal/openai"
+)
+
+// Result é o retorno estruturado de Run e RouteAndRun.
+type Result struct {
+	Text       string        `json:"final_text"`
+	Model      string        `json:"model"`
+	BasePrompt string        `json:"base_prompt"`
+	Route      *Route        `json:"route,omitempty"`
+	ToolCalls  []ToolCall    `json:"tool_calls,omitempty"`
+	StopReason string        `json:"stop_reason,omitempty"`
+	Facts      []memory.Fact `json:"facts,omitempty"`
+
+	Context *ContextReport `json:"context,omitempty"`
+
+	SummaryUpdated bool         `json:"summary_updated,omitempty"`
+	Usage          openai.Usage `json:"usage"`
+	ResponseIDs    []string     `json:"response_ids,omitempty"`
+	MessageIDs     []int64      `json:"message_ids,omitempty"`
+
+	// Verbose faz String() renderizar o JSON completo em vez de só o texto.
+	Verbose bool `json:"-"`
+}
+
+// ToolCall é uma tool executada durante o loop.
+type ToolCall struct {
+	Step   int      `json:"step"`
+	Name   string   `json:"tool_requested"`
+	Args   []string `json:"tool_args,omitempty"`
+	Output string   `json:"tool_output"`
+	Failed bool     `json:"failed,omitempty"`
+	// Denied indica que a tool estava fora da lista permitida para o prompt e
+	// não foi executada.
+	Denied bool `json:"denied,omitempty"`
+	// Truncated indica que a saída passou do orçamento e foi cortada.
+	Truncated bool          `json:"truncated,omitempty"`
+	Duration  time.Duration `json:"duration"`
+}
+
+// Route descreve a decisão do router em RouteAndRun.
+type Route struct {
+	RouterPath string   `json:"router_path"`
+	Candidates []string `json:"candidates,omitempty"`
+	Raw        string   `json:"router_raw,omitempty"`
+	Error      string   `json:"router_error,omitempty"`
+	Chosen     string   `json:"chosen,omitempty"`
+	PromptPath string   `json:"special_prompt,omitempty"`
+	// Description vem do front matter do prompt escolhido.
+	Description string `json:"description,omitempty"`
+
+	// Mode é RouterLLM ou RouterEmbedding. Scores é o ranking dos candidatos:
+	// confiança de 0 a 1 no modo llm, similaridade de cosseno no modo embedding.
+	// TieBreak indica que o LLM desempatou os dois melhores por embedding.
+	Mode       string       `json:"mode"`
+	Scores     []RouteScore `json:"scores,omitempty"`
+	Confidence float64      `json:"confidence"`
+	TieBreak   bool         `json:"tie_break,omitempty"`
+	// Attempts conta as perguntas ao router, incluindo as repetidas após
+	// respostas inválidas.
+	Attempts int `json:"attempts,omitempty"`
+
+	// LowConfidence indica que a confiança ficou abaixo do limite; nesse caso
+	// a rota é a de fallback ou, se configurado, o agent devolve Clarification.
+	LowConfidence bool   `json:"low_confidence,omitempty"`
+	Fallback      bool   `json:"fallback,omitempty"`
+	Clarification string `json:"clarification,omitempty"`
+
+	// Previous é a rota da sessão antes desta mensagem. Sticky indica que ela
+	// foi mantida porque o router não estava confiante para trocar; Pinned, que
+	// a rota foi fixada por ForceRoute e o router nem foi consultado.
+	Previous string `json:"previous,omitempty"`
+	Sticky   bool   `json:"sticky,omitempty"`
+	Pinned   bool   `json:"pinned,omitempty"`
+
+	// Trace tem a decisão de cada nível da árvore de prompts, da raiz até a
+	// folha. Os campos acima repetem o último nível; Chosen é o caminho completo
+	// (ex.: "financeiro/boleto.md").
+	Trace []Route `json:"trace,omitempty"`
+
+	// Context é o corte aplicado à memória enviada ao router.
+	Context *ContextReport `json:"context,omitempty"`
+}
+
+// chosen devolve o prompt escolhido, ou "" sem router.
+func (r *Route) chosen() string {
+	if r == nil {
+		return ""
+	}
+	return r.Chosen
+}
+
+// merge copia para a rota a decisão de um nível.
+func (r *Route) merge(step *Route) {
+	r.Candidates = step.Candidates
+	r.Raw = step.Raw
+	r.Error = step.Error
+	r.Mode = step.Mode
+	r.Scores = step.Scores
+	r.Confidence = step.Confidence
+	r.TieBreak = step.TieBreak
+	r.Attempts = step.Attempts
+	r.LowConfidence = step.LowConfidence
+	r.Fallback = step.Fallback
+	r.Clarification = step.Clarification
+	r.Previous = step.Previous
+	r.Sticky = step.Sticky
+	r.Pinned = step.Pinned
+}
+
+func (r *Result) JSON() string {
+	js, _ := json.MarshalIndent(r, "", "  ")
+	return string(js)
+}
+
+func (r *Result) String() string {
+	if r.Verbose {
+		return r.JSON()
+	}
+	return r.Text
+}
+
+// track embrulha respond para acumular uso de tokens e IDs das respostas.
+func (r *Result) track(respond respondFunc) respondFunc {
+	return func(ctx context.Context, req *openai.ResponsesRequest) (*openai.ResponseEnvelope, error) {
+		resp, err := respond(ctx, req)
+		if resp != nil {
+			r.record(resp)
+		}
+		return resp, err
+	}
+}
+
+func (r *Result) record(resp *openai.ResponseEnvelope) {
+	r.Usage.Add(resp.Usage)
+	if resp.ID != "" {
+		r.ResponseIDs = append(r.ResponseIDs, resp.ID)
+	}
+}
@@ -1,10 +1,12 @@
 package agent
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 	"sort"
@@ -25,62 +27,282 @@ func envIntR(key string, def int) int {
 	return def
 }
 
+// maxRouteDepth limita a descida na árvore de prompts.
+const maxRouteDepth = 8
+
+// ErrNoRoute é o erro base de NoRouteError, para uso com errors.Is.
+var ErrNoRoute = errors.New("no route")
+
+// NoRouteError é devolvido por RouteAndRun quando o router não chega a uma rota
+// e a configuração pede erro em vez de fallback (ou o fallback não existe).
+// Route tem a decisão até o nível que falhou.
+type NoRouteError struct {
+	RouterPath string
+	Reason     string
+	Route      *Route
+}
+
+func (e *NoRouteError) Error() string {
+	return "no route in " + e.RouterPath + ": " + e.Reason
+}
+
+func (e *NoRouteError) Unwrap() error { return ErrNoRoute }
+
+// routerSettings é a configuração de fallback de um nível: o front matter do
+// router.md tem prioridade sobre as opções.
+type routerSettings struct {
+	fallback  string
+	retries   int
+	onNoRoute string
+}
+
+func (b *builder) routerSettings(meta promptMeta) routerSettings {
+	rc := routerSettings{
+		fallback:  cmp.Or(meta.Fallback, b.routerFallback, defaultFallback),
+		retries:   1,
+		onNoRoute: cmp.Or(meta.OnNoRoute, b.routerOnNoRoute, OnNoRouteFallback),
+	}
+	if b.routerRetries != 0 {
+		rc.retries = max(b.routerRetries, 0)
+	}
+	if meta.Retries != nil {
+		rc.retries = max(*meta.Retries, 0)
+	}
+	return rc
+}
+
 func RouteAndRun(
 	ctx context.Context,
-	cli *openai.Client,
+	rt *Runtime,
 	model string,
 	embeddingModel string,
 	sessionID string,
 	basePromptPath string,
 	userMessage string,
 	routerPath string,
-	verbose bool,
 	opts ...Option,
-) (string, error) {
+) (*Result, error) {
 	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
 		var cancel context.CancelFunc
 		ctx, cancel = context.WithTimeout(ctx, 60*time.Second)
 		defer cancel()
 	}
 
-	vr := routeVerbose{
-		RouterEnabled: routerPath != "",
-		RouterPath:    routerPath,
-		BasePrompt:    basePromptPath,
-		UserMessage:   userMessage,
+	if routerPath == "" {
+		return Run(ctx, rt, model, embeddingModel, sessionID, basePromptPath, userMessage, opts...)
 	}
 
-	if routerPath == "" {
-		return Run(ctx, cli, model, embeddingModel, sessionID, basePromptPath, userMessage, verbose, opts...)
+	res := &Result{BasePrompt: basePromptPath}
+	route := &Route{RouterPath: routerPath}
+	res.Route = route
+
+	cfg := applyOptions(opts)
+	mode := cfg.routerMode
+	if mode == "" {
+		mode = os.Getenv("ROUTER_MODE")
+	}
+	if mode == "" {
+		mode = RouterLLM
 	}
 
-	routerBytes, err := os.ReadFile(routerPath)
+	// a memória da sessão só é montada se algum nível consultar o router, e uma
+	// vez só para todos os níveis
+	var (
+		userEmb     []float32
+		routerInput string
+		loaded      bool
+	)
+	input := func(routerPrompt string) ([]float32, string) {
+		if !loaded {
+			userEmb, routerInput = routerMemory(ctx, rt, cfg.budget, embeddingModel, sessionID, userMessage, routerPrompt, route)
+			loaded = true
+		}
+		return userEmb, routerInput
+	}
+
+	var (
+		leaf   *promptFile
+		prefix string
+		path   = routerPath
+	)
+	for depth := 0; leaf == nil; depth++ {
+		if depth >= maxRouteDepth {
+			return nil, fmt.Errorf("router tree deeper than %d levels at %s", maxRouteDepth, path)
+		}
+		step, chosen, err := routeLevel(ctx, rt, res, cfg, mode, model, embeddingModel, sessionID, path, input)
+		if err != nil {
+			var noRoute *NoRouteError
+			if errors.As(err, &noRoute) {
+				route.Trace = append(route.Trace, *step)
+				route.merge(step)
+				noRoute.Route = route
+			}
+			return nil, err
+		}
+		if chosen != nil {
+			step.Chosen = prefix + chosen.Name
+		}
+		route.Trace = append(route.Trace, *step)
+		if chosen == nil {
+			// o nível pediu esclarecimento ao usuário
+			route.merge(step)
+			return clarify(rt, embeddingModel, sessionID, userMessage, userEmb, res)
+		}
+		if chosen.Category {
+			prefix += chosen.Name
+			path = chosen.Path
+			continue
+		}
+		leaf = chosen
+	}
+
+	last := route.Trace[len(route.Trace)-1]
+	route.merge(&last)
+	route.Chosen = prefix + leaf.Name
+	route.PromptPath = leaf.Path
+	route.Description = leaf.Meta.Description
+
+	routeOpts := append(leaf.Meta.options(), WithSystemPrompt(leaf.Body))
+	return run(ctx, rt, model, embeddingModel, sessionID, basePromptPath, userMessage, res, append(opts, routeOpts...)...)
+}
+
+// routeLevel decide um nível da árvore: escolhe um prompt ou uma categoria
+// (subdiretório com router.md) entre os candidatos de routerPath. Devolve nil
+// quando o nível deve responder com uma pergunta de esclarecimento.
+func routeLevel(
+	ctx context.Context,
+	rt *Runtime,
+	res *Result,
+	cfg *builder,
+	mode string,
+	model string,
+	embeddingModel string,
+	sessionID string,
+	routerPath string,
+	input func(routerPrompt string) ([]float32, string),
+) (*Route, *promptFile, error) {
+	step := &Route{RouterPath: routerPath, Mode: mode}
+
+	router, err := loadPrompt(routerPath)
 	if err != nil {
-		return "", fmt.Errorf("read router: %w", err)
+		return nil, nil, fmt.Errorf("read router: %w", err)
 	}
-	routerPrompt := string(routerBytes)
+	routerPrompt := router.Body
+	rc := cfg.routerSettings(router.Meta)
 
 	dir := filepath.Dir(routerPath)
-	cands, err := listPromptCandidates(dir)
+	prompts, err := listPromptCandidates(dir)
 	if err != nil {
-		return "", err
+		return nil, nil, err
 	}
+	cands := candidateNames(prompts)
 	if len(cands) == 0 {
-		return "", fmt.Errorf("no candidates in %s", dir)
+		return nil, nil, fmt.Errorf("no candidates in %s", dir)
+	}
+	step.Candidates = cands
+
+	current, _ := rt.Memory.LoadRoute(ctx, sessionID, routerPath)
+	if current != nil {
+		if c, ok := matchCandidate(current.Prompt, cands); ok {
+			current.Prompt = c
+			step.Previous = c
+		} else {
+			current = nil
+		}
+	}
+	if current != nil && current.Pinned {
+		step.Pinned = true
+		return step, findPrompt(prompts, current.Prompt), nil
+	}
+
+	userEmb, routerInput := input(routerPrompt)
+
+	var chosen string
+	respond := res.track(rt.LLM.Respond)
+	switch mode {
+	case RouterEmbedding:
+		chosen, err = embeddingRoute(ctx, rt, respond, model, embeddingModel, userEmb, step, prompts, routerPrompt, routerInput, cfg.routerTieMargin, rc.retries)
+	case RouterLLM:
+		chosen, err = llmRoute(ctx, respond, model, step, routerPrompt, routerInput, prompts, rc.retries)
+	default:
+		err = fmt.Errorf("unknown router mode %q", mode)
+	}
+
+	// com rota atual na sessão, só troca quando o router está confiante
+	keep := false
+	if current != nil && cfg.routerSwitch > 0 {
+		switch {
+		case err == nil && chosen == current.Prompt:
+			keep = true
+		case err != nil || step.Confidence < cfg.routerSwitch:
+			if err != nil {
+				step.Error = err.Error()
+			}
+			keep = true
+			step.Sticky = true
+			chosen, err = current.Prompt, nil
+		}
 	}
-	vr.Candidates = append(vr.Candidates, cands...)
 
-	mem := memory.Get()
+	if !keep && err == nil && cfg.routerThreshold > 0 && step.Confidence < cfg.routerThreshold {
+		step.LowConfidence = true
+		if cfg.routerOnLow == LowConfidenceClarify {
+			if step.Clarification == "" {
+				step.Clarification = defaultClarification(prompts, step.Scores)
+			}
+			return step, nil, nil
+		}
+		err = fmt.Errorf("router confidence %.2f below threshold %.2f", step.Confidence, cfg.routerThreshold)
+	}
+	if err != nil {
+		step.Error = err.Error()
+		if rc.onNoRoute == OnNoRouteError {
+			return step, nil, &NoRouteError{RouterPath: routerPath, Reason: err.Error()}
+		}
+		fb, ok := matchCandidate(rc.fallback, cands)
+		switch {
+		case ok:
+			chosen = fb
+		case rc.fallback != defaultFallback:
+			return step, nil, &NoRouteError{RouterPath: routerPath, Reason: "fallback " + rc.fallback + " not found"}
+		default:
+			chosen = fallbackCandidate(cands, defaultFallback)
+		}
+		step.Fallback = true
+	}
+
+	if !step.Fallback {
+		_ = rt.Memory.SaveRoute(ctx, sessionID, memory.SessionRoute{RouterPath: routerPath, Prompt: chosen})
+	}
+	return step, findPrompt(prompts, chosen), nil
+}
+
+// routerMemory monta a entrada do router com a memória da sessão e devolve
+// também o embedding da mensagem, reaproveitado pelo modo embedding.
+func routerMemory(
+	ctx context.Context,
+	rt *Runtime,
+	budget ContextBudget,
+	embeddingModel string,
+	sessionID string,
+	userMessage string,
+	routerPrompt string,
+	route *Route,
+) ([]float32, string) {
+	mem := rt.Memory
 	semTopK := envIntR("MEM_SEM_TOPK", 5)
 	memDepth := envIntR("MEM_DEPTH", 4)
 
 	var (
+		userEmb   []float32
 		retrieved []memory.HistoryItem
 		recent    []memory.HistoryItem
-		faturas   map[string]string
+		facts     []memory.Fact
+		summary   *memory.Summary
 	)
 
-	if emb, errEmb := cli.Embed(ctx, embeddingModel, userMessage); errEmb == nil {
+	if emb, errEmb := rt.LLM.Embed(ctx, embeddingModel, userMessage); errEmb == nil {
+		userEmb = emb
 		if items, err := mem.RetrieveSimilar(ctx, sessionID, emb, semTopK); err == nil {
 			retrieved = items
 		}
@@ -90,22 +312,38 @@ func RouteAndRun(
 		recent = items
 	}
 
-	if m, err := mem.LoadBoletoStatus(ctx, sessionID); err == nil {
-		faturas = m
+	if items, err := mem.LoadFacts(ctx, sessionID); err == nil {
+		facts = items
+	}
+
+	if sum, err := mem.LoadSummary(ctx, sessionID); err == nil {
+		summary = sum
 	}
 
+	asm := newAssembler(rt.Tokenizer, budget)
+	parts, report := asm.fit(contextParts{
+		fixed:   asm.tok.Count(routerPrompt),
+		summary: summary,
+		recent:  recent,
+		similar: retrieved,
+		facts:   facts,
+	})
+	route.Context = report
+	summary, recent, retrieved, facts = parts.summary, parts.recent, parts.similar, parts.facts
+
 	var sb strings.Builder
+	if summary != nil && summary.Text != "" {
+		sb.WriteString("== Resumo ==\n" + summary.Text + "\n\n")
+	}
 	if len(recent) > 0 {
 		sb.WriteString("== Memória curta ==\n")
 		for _, h := range recent {
 			sb.WriteString(h.Role + ": " + h.Text + "\n")
 		}
 	}
-	if len(faturas) > 0 {
-		sb.WriteString("\n== Fatos: faturas ==\n")
-		for id, st := range faturas {
-			sb.WriteString("- " + id + ": " + st + "\n")
-		}
+	if len(facts) > 0 {
+		sb.WriteString("\n== Fatos ==\n")
+		writeFacts(&sb, facts)
 	}
 	if len(retrieved) > 0 {
 		sb.WriteString("\n== Semântica relevante ==\n")
@@ -115,111 +353,104 @@ func RouteAndRun(
 	}
 	memBlock := sb.String()
 
-	routerInput := userMessage
-	if memBlock != "" {
-		routerInput = memBlock + "\nUsuário agora: " + userMessage
+	if memBlock == "" {
+		return userEmb, userMessage
 	}
+	return userEmb, memBlock + "\nUsuário agora: " + userMessage
+}
 
-	chosen, raw, err := askRouter(ctx, cli, model, routerPrompt, routerInput, cands)
-	vr.RouterRaw = raw
-	if err != nil {
-		vr.RouterError = err.Error()
-		chosen = fallbackCandidate(cands, "geral.md")
-	}
-	vr.Chosen = chosen
-	vr.SpecialPrompt = filepath.Join(dir, chosen)
+// CurrentRoute devolve a rota atual da sessão para o router, ou nil. Em uma
+// árvore de prompts é a escolha do primeiro nível (ex.: "financeiro/").
+func CurrentRoute(ctx context.Context, rt *Runtime, sessionID, routerPath string) (*memory.SessionRoute, error) {
+	return rt.Memory.LoadRoute(ctx, sessionID, routerPath)
+}
 
-	specBytes, err := os.ReadFile(vr.SpecialPrompt)
-	if err != nil {
-		vr.RouterError = "chosen prompt not found: " + err.Error()
-		chosen = fallbackCandidate(cands, "geral.md")
-		vr.Chosen = chosen
-		vr.SpecialPrompt = filepath.Join(dir, chosen)
-		specBytes, err = os.ReadFile(vr.SpecialPrompt)
+// ForceRoute fixa a rota da sessão: enquanto fixada, RouteAndRun não consulta o
+// router. prompt é relativo ao diretório do router e pode descer na árvore
+// ("financeiro/boleto.md" fixa os dois níveis). Vazio libera a sessão em todos
+// os níveis.
+func ForceRoute(ctx context.Context, rt *Runtime, sessionID, routerPath, prompt string) error {
+	if prompt == "" {
+		return unpinRoutes(ctx, rt, sessionID, routerPath, 0)
+	}
+	path := routerPath
+	rest := prompt
+	for rest != "" {
+		prompts, err := listPromptCandidates(filepath.Dir(path))
 		if err != nil {
-			return "", fmt.Errorf("read chosen prompt: %w", err)
+			return err
+		}
+		name := rest
+		if i := strings.IndexByte(rest, '/'); i >= 0 {
+			name, rest = rest[:i+1], rest[i+1:]
+		} else {
+			rest = ""
+		}
+		c, ok := matchCandidate(name, candidateNames(prompts))
+		if !ok {
+			return fmt.Errorf("route %q not found next to %s", name, path)
 		}
+		if err := rt.Memory.SaveRoute(ctx, sessionID, memory.SessionRoute{RouterPath: path, Prompt: c, Pinned: true}); err != nil {
+			return err
+		}
+		p := findPrompt(prompts, c)
+		if !p.Category {
+			if rest != "" {
+				return fmt.Errorf("route %q is a prompt, not a category", c)
+			}
+			return nil
+		}
+		path = p.Path
 	}
-	specPrompt := string(specBytes)
+	return nil
+}
 
-	runOut, err := Run(ctx, cli, model, embeddingModel, sessionID, basePromptPath, userMessage, verbose, append(opts, WithSystemPrompt(specPrompt))...)
+func unpinRoutes(ctx context.Context, rt *Runtime, sessionID, routerPath string, depth int) error {
+	if depth >= maxRouteDepth {
+		return nil
+	}
+	if err := rt.Memory.SaveRoute(ctx, sessionID, memory.SessionRoute{RouterPath: routerPath}); err != nil {
+		return err
+	}
+	prompts, err := listPromptCandidates(filepath.Dir(routerPath))
 	if err != nil {
-		return "", err
+		return err
 	}
-	vr.FinalText = runOut
-
-	if verbose {
-		var rv runVerbose
-		_ = json.Unmarshal([]byte(runOut), &rv)
-
-		type merged struct {
-			RouterEnabled bool     `json:"router_enabled"`
-			RouterPath    string   `json:"router_path,omitempty"`
-			BasePrompt    string   `json:"base_prompt"`
-			UserMessage   string   `json:"user_message"`
-			Candidates    []string `json:"candidates,omitempty"`
-			RouterRaw     string   `json:"router_raw,omitempty"`
-			RouterError   string   `json:"router_error,omitempty"`
-			Chosen        string   `json:"chosen,omitempty"`
-			SpecialPrompt string   `json:"special_prompt,omitempty"`
-			ToolRequested string   `json:"tool_requested,omitempty"`
-			ToolArgs      []string `json:"tool_args,omitempty"`
-			ToolOutput    string   `json:"tool_output,omitempty"`
-			FinalText     string   `json:"final_text"`
-		}
-		out := merged{
-			RouterEnabled: vr.RouterEnabled,
-			RouterPath:    vr.RouterPath,
-			BasePrompt:    vr.BasePrompt,
-			UserMessage:   vr.UserMessage,
-			Candidates:    vr.Candidates,
-			RouterRaw:     vr.RouterRaw,
-			RouterError:   vr.RouterError,
-			Chosen:        vr.Chosen,
-			SpecialPrompt: vr.SpecialPrompt,
-			FinalText:     vr.FinalText,
-		}
-		if rv.FinalText != "" || rv.ToolRequested != "" {
-			out.ToolRequested = rv.ToolRequested
-			out.ToolArgs = rv.ToolArgs
-			out.ToolOutput = rv.ToolOutput
-			out.FinalText = rv.FinalText
-		}
-		js, _ := json.MarshalIndent(out, "", "  ")
-		return string(js), nil
-	}
-	return runOut, nil
-}
-
-type routeVerbose struct {
-	RouterEnabled bool     `json:"router_enabled"`
-	RouterPath    string   `json:"router_path,omitempty"`
-	BasePrompt    string   `json:"base_prompt"`
-	UserMessage   string   `json:"user_message"`
-	Candidates    []string `json:"candidates,omitempty"`
-	RouterRaw     string   `json:"router_raw,omitempty"`
-	RouterError   string   `json:"router_error,omitempty"`
-	Chosen        string   `json:"chosen,omitempty"`
-	SpecialPrompt string   `json:"special_prompt,omitempty"`
-	FinalText     string   `json:"final_text"`
-}
-
-func (r routeVerbose) JSON() string {
-	js, _ := json.MarshalIndent(r, "", "  ")
-	return string(js)
+	for _, p := range prompts {
+		if p.Category {
+			if err := unpinRoutes(ctx, rt, sessionID, p.Path, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
-func listPromptCandidates(dir string) ([]string, error) {
+// listPromptCandidates carrega os candidatos do diretório do router, com o
+// front matter de cada um: os prompts .md e, como categorias, os subdiretórios
+// que têm o próprio router.md (nome terminado em "/").
+func listPromptCandidates(dir string) ([]*promptFile, error) {
 	entries, err := os.ReadDir(dir)
 	if err != nil {
 		return nil, fmt.Errorf("list router dir: %w", err)
 	}
-	var files []string
+	var prompts []*promptFile
 	for _, e := range entries {
+		name := e.Name()
 		if e.IsDir() {
+			sub := filepath.Join(dir, name, "router.md")
+			if _, err := os.Stat(sub); err != nil {
+				continue
+			}
+			p, err := loadPrompt(sub)
+			if err != nil {
+				return nil, err
+			}
+			p.Name = name + "/"
+			p.Category = true
+			prompts = append(prompts, p)
 			continue
 		}
-		name := e.Name()
 		low := strings.ToLower(name)
 		if !strings.HasSuffix(low, ".md") {
 			continue
@@ -227,10 +458,31 @@ func listPromptCandidates(dir string) ([]string, error) {
 		if low == "router.md" {
 			continue
 		}
-		files = append(files, name)
+		p, err := loadPrompt(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		prompts = append(prompts, p)
+	}
+	sort.Slice(prompts, func(i, j int) bool { return prompts[i].Name < prompts[j].Name })
+	return prompts, nil
+}
+
+func candidateNames(prompts []*promptFile) []string {
+	names := make([]string, len(prompts))
+	for i, p := range prompts {
+		names[i] = p.Name
+	}
+	return names
+}
+
+func findPrompt(prompts []*promptFile, name string) *promptFile {
+	for _, p := range prompts {
+		if p.Name == name {
+			return p
+		}
 	}
-	sort.Strings(files)
-	return files, nil
+	return nil
 }
 
 func normalizeChoice(s string) string {
@@ -269,34 +521,96 @@ func matchCandidate(raw string, candidates []string) (string, bool) {
 	return "", false
 }
 
+const defaultFallback = "geral.md"
+
 func fallbackCandidate(candidates []string, prefer string) string {
 	for _, c := range candidates {
 		if strings.EqualFold(c, prefer) {
 			return c
 		}
 	}
+	// sem o preferido, um prompt vem antes de uma categoria
+	for _, c := range candidates {
+		if !strings.HasSuffix(c, "/") {
+			return c
+		}
+	}
 	return candidates[0]
 }
 
+// routerReply é a saída estruturada pedida ao router.
+type routerReply struct {
+	Ranking []struct {
+		Candidate  string  `json:"candidate"`
+		Confidence float64 `json:"confidence"`
+	} `json:"ranking"`
+	ClarifyingQuestion string `json:"clarifying_question"`
+}
+
+func routerSchema(candidates []string) map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"ranking": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"candidate":  map[string]any{"type": "string", "enum": candidates},
+						"confidence": map[string]any{"type": "number"},
+					},
+					"required":             []string{"candidate", "confidence"},
+					"additionalProperties": false,
+				},
+			},
+			"clarifying_question": map[string]any{"type": "string"},
+		},
+		"required":             []string{"ranking", "clarifying_question"},
+		"additionalProperties": false,
+	}
+}
+
+// routerAnswer é o que o router respondeu; Attempts conta as tentativas,
+// incluindo as repetidas após respostas inválidas.
+type routerAnswer struct {
+	Ranking  []RouteScore
+	Question string
+	Raw      string
+	Attempts int
+}
+
+const routerRetryMsg = "Sua resposta anterior não é válida: %s. Responda novamente no formato pedido, usando apenas estas opções: %s."
+
+// askRouter pede ao modelo o ranking dos candidatos com a confiança de cada um,
+// do mais para o menos adequado, e uma pergunta de esclarecimento opcional.
+// Respostas sem nenhum candidato válido são repetidas até retries vezes com uma
+// mensagem corretiva.
 func askRouter(
 	ctx context.Context,
-	cli *openai.Client,
+	respond respondFunc,
 	model string,
 	routerPrompt string,
 	userMessage string,
-	candidates []string,
-) (chosen string, raw string, err error) {
+	prompts []*promptFile,
+	retries int,
+) (routerAnswer, error) {
+	candidates := candidateNames(prompts)
 	var sb strings.Builder
 	sb.WriteString(routerPrompt)
 	sb.WriteString("\n\n== Regras de roteamento ==\n")
-	sb.WriteString("Escolha exatamente UM dos seguintes arquivos de prompt e responda SOMENTE com o nome do arquivo.\n")
+	sb.WriteString("Classifique a mensagem do usuário entre os arquivos de prompt abaixo.\n")
 	sb.WriteString("Opções permitidas:\n")
-	for _, c := range candidates {
+	for _, p := range prompts {
 		sb.WriteString("- ")
-		sb.WriteString(c)
+		sb.WriteString(p.Name)
+		if d := strings.TrimSpace(p.Meta.Description); d != "" {
+			sb.WriteString(": ")
+			sb.WriteString(d)
+		}
 		sb.WriteByte('\n')
 	}
-	sb.WriteString("\nFormato de saída: apenas o nome do arquivo (ex.: tecnico.md). Não inclua explicações.\n")
+	sb.WriteString("\nEm ranking, liste os arquivos do mais para o menos adequado, com confidence entre 0 e 1 para cada um.\n")
+	sb.WriteString("Se a mensagem for ambígua entre as opções, escreva em clarifying_question uma pergunta curta ao usuário para decidir; caso contrário deixe vazio.\n")
 
 	sys := openai.Message{
 		Type: "message",
@@ -316,16 +630,109 @@ func askRouter(
 	req := &openai.ResponsesRequest{
 		Model:           model,
 		Input:           []openai.Message{sys, user},
-		MaxOutputTokens: 32,
+		MaxOutputTokens: 200 + 30*len(candidates),
+		Text:            openai.JSONSchemaFormat("route_ranking", routerSchema(candidates)),
 	}
 
-	resp, e := cli.Respond(ctx, req)
-	if e != nil {
-		return "", "", e
+	var ans routerAnswer
+	for {
+		ans.Attempts++
+		resp, err := respond(ctx, req)
+		if err != nil {
+			return ans, err
+		}
+		ans.Raw = resp.OutputText
+		ans.Ranking, ans.Question = parseRouterReply(ans.Raw, candidates)
+		if len(ans.Ranking) > 0 {
+			return ans, nil
+		}
+		if ans.Attempts > retries {
+			return ans, errors.New("router returned an invalid option")
+		}
+		req.Input = append(req.Input,
+			openai.Message{
+				Type:    "message",
+				Role:    "assistant",
+				Content: []openai.ContentItem{{Type: "output_text", Text: ans.Raw}},
+			},
+			openai.Message{
+				Type: "message",
+				Role: "user",
+				Content: []openai.ContentItem{{
+					Type: "input_text",
+					Text: fmt.Sprintf(routerRetryMsg, "nenhuma opção reconhecida", strings.Join(candidates, ", ")),
+				}},
+			},
+		)
 	}
-	raw = resp.OutputText
-	if sel, ok := matchCandidate(resp.OutputText, candidates); ok {
-		return sel, raw, nil
+}
+
+// parseRouterReply lê a resposta estruturada. Providers sem saída estruturada
+// podem responder só o nome do arquivo; nesse caso a confiança fica em 1.
+func parseRouterReply(raw string, candidates []string) ([]RouteScore, string) {
+	var reply routerReply
+	if err := json.Unmarshal([]byte(strings.TrimSpace(raw)), &reply); err != nil {
+		if sel, ok := matchCandidate(raw, candidates); ok {
+			return []RouteScore{{Candidate: sel, Score: 1}}, ""
+		}
+		return nil, ""
+	}
+	seen := map[string]bool{}
+	var ranking []RouteScore
+	for _, r := range reply.Ranking {
+		sel, ok := matchCandidate(r.Candidate, candidates)
+		if !ok || seen[sel] {
+			continue
+		}
+		seen[sel] = true
+		ranking = append(ranking, RouteScore{Candidate: sel, Score: math.Max(0, math.Min(1, r.Confidence))})
+	}
+	sort.SliceStable(ranking, func(i, j int) bool { return ranking[i].Score > ranking[j].Score })
+	return ranking, strings.TrimSpace(reply.ClarifyingQuestion)
+}
+
+// llmRoute pede o ranking ao modelo e escolhe o primeiro.
+func llmRoute(ctx context.Context, respond respondFunc, model string, route *Route, routerPrompt, routerInput string, prompts []*promptFile, retries int) (string, error) {
+	ans, err := askRouter(ctx, respond, model, routerPrompt, routerInput, prompts, retries)
+	route.Raw = ans.Raw
+	route.Attempts = ans.Attempts
+	route.Clarification = ans.Question
+	if err != nil {
+		return "", err
 	}
-	return "", raw, errors.New("router returned an invalid option")
+	route.Scores = ans.Ranking
+	route.Confidence = ans.Ranking[0].Score
+	return ans.Ranking[0].Candidate, nil
+}
+
+// defaultClarification pergunta entre os dois melhores candidatos, pela
+// description do front matter ou pelo nome do arquivo.
+func defaultClarification(prompts []*promptFile, scores []RouteScore) string {
+	var opts []string
+	for _, s := range scores {
+		if len(opts) == 2 {
+			break
+		}
+		label := strings.TrimSuffix(s.Candidate, filepath.Ext(s.Candidate))
+		if p := findPrompt(prompts, s.Candidate); p != nil && p.Meta.Description != "" {
+			label = strings.TrimSpace(p.Meta.Description)
+		}
+		opts = append(opts, label)
+	}
+	if len(opts) < 2 {
+		return "Não tenho certeza se entendi. Pode detalhar um pouco mais o que você precisa?"
+	}
+	return "Não tenho certeza se entendi. Sua dúvida é sobre " + opts[0] + " ou sobre " + opts[1] + "?"
+}
+
+// clarify responde com a pergunta de esclarecimento do router em vez de rodar
+// uma rota. A troca fica salva na memória como um turno normal.
+func clarify(rt *Runtime, embeddingModel, sessionID, userMessage string, userEmb []float32, res *Result) (*Result, error) {
+	res.Text = res.Route.Clarification
+	res.StopReason = stopClarify
+	st := &runState{mem: rt.Memory, userEmb: userEmb}
+	if err := st.persist(rt, embeddingModel, sessionID, userMessage, res, &openai.ResponseEnvelope{}); err != nil {
+		return nil, err
+	}
+	return res, nil
 }
@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/RafaelZelak/agentkit/internal/memory"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Modos do router em RouteAndRun.
+const (
+	RouterLLM       = "llm"
+	RouterEmbedding = "embedding"
+)
+
+// RouteScore é a similaridade entre a mensagem e um candidato.
+type RouteScore struct {
+	Candidate string  `json:"candidate"`
+	Score     float64 `json:"score"`
+}
+
+// embeddingCache guarda os vetores dos textos das rotas por modelo e texto, então
+// editar um prompt só recalcula o que mudou.
+type embeddingCache struct {
+	mu   sync.Mutex
+	vecs map[string][]float32
+}
+
+func (c *embeddingCache) embed(ctx context.Context, rt *Runtime, model, text string) ([]float32, error) {
+	key := model + "\x00" + text
+	c.mu.Lock()
+	v, ok := c.vecs[key]
+	c.mu.Unlock()
+	if ok {
+		return v, nil
+	}
+	v, err := rt.LLM.Embed(ctx, model, text)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	if c.vecs == nil {
+		c.vecs = map[string][]float32{}
+	}
+	c.vecs[key] = v
+	c.mu.Unlock()
+	return v, nil
+}
+
+// rankByEmbedding pontua cada candidato pelo maior cosseno entre a mensagem e
+// seus textos de rota, do mais para o menos parecido.
+func rankByEmbedding(ctx context.Context, rt *Runtime, embeddingModel string, userEmb []float32, prompts []*promptFile) ([]RouteScore, error) {
+	scores := make([]RouteScore, len(prompts))
+	var mu sync.Mutex
+	eg, egctx := errgroup.WithContext(ctx)
+	eg.SetLimit(4)
+	for i, p := range prompts {
+		scores[i].Candidate = p.Name
+		for _, text := range p.routeTexts() {
+			eg.Go(func() error {
+				v, err := rt.embeddings.embed(egctx, rt, embeddingModel, text)
+				if err != nil {
+					return err
+				}
+				s := memory.Cosine(userEmb, v)
+				mu.Lock()
+				if s > scores[i].Score {
+					scores[i].Score = s
+				}
+				mu.Unlock()
+				return nil
+			})
+		}
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(scores, func(i, j int) bool { return scores[i].Score > scores[j].Score })
+	return scores, nil
+}
+
+// embeddingRoute escolhe o candidato mais próximo da mensagem. Com tieMargin > 0
+// e os dois primeiros a menos disso um do outro, o LLM desempata entre eles.
+func embeddingRoute(
+	ctx context.Context,
+	rt *Runtime,
+	respond respondFunc,
+	model string,
+	embeddingModel string,
+	userEmb []float32,
+	route *Route,
+	prompts []*promptFile,
+	routerPrompt string,
+	routerInput string,
+	tieMargin float64,
+	retries int,
+) (string, error) {
+	if len(userEmb) == 0 {
+		return "", errors.New("router: embedding of the message is unavailable")
+	}
+	scores, err := rankByEmbedding(ctx, rt, embeddingModel, userEmb, prompts)
+	if err != nil {
+		return "", err
+	}
+	route.Scores = scores
+	route.Confidence = scores[0].Score
+	if tieMargin <= 0 || len(scores) < 2 || scores[0].Score-scores[1].Score >= tieMargin {
+		return scores[0].Candidate, nil
+	}
+
+	route.TieBreak = true
+	top := []*promptFile{findPrompt(prompts, scores[0].Candidate), findPrompt(prompts, scores[1].Candidate)}
+	ans, err := askRouter(ctx, respond, model, routerPrompt, routerInput, top, retries)
+	route.Raw = ans.Raw
+	route.Attempts = ans.Attempts
+	route.Clarification = ans.Question
+	if err != nil || ans.Ranking[0].Candidate == scores[0].Candidate {
+		// sem desempate válido fica o mais próximo
+		return scores[0].Candidate, nil
+	}
+	route.Confidence = scores[1].Score
+	return scores[1].Candidate, nil
+}
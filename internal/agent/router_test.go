@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRouterReply(t *testing.T) {
+	candidates := []string{"boletos.md", "geral.md", "suporte.md"}
+	tests := []struct {
+		name         string
+		raw          string
+		wantRanking  []RouteScore
+		wantQuestion string
+	}{
+		{
+			name: "ranking ordenado por confiança",
+			raw:  `{"ranking":[{"candidate":"geral.md","confidence":0.2},{"candidate":"boletos.md","confidence":0.9}],"clarifying_question":""}`,
+			wantRanking: []RouteScore{
+				{Candidate: "boletos.md", Score: 0.9},
+				{Candidate: "geral.md", Score: 0.2},
+			},
+		},
+		{
+			name: "nome sem .md, repetido e desconhecido",
+			raw:  `{"ranking":[{"candidate":"Boletos","confidence":0.7},{"candidate":"boletos.md","confidence":0.6},{"candidate":"vendas.md","confidence":0.5}]}`,
+			wantRanking: []RouteScore{
+				{Candidate: "boletos.md", Score: 0.7},
+			},
+		},
+		{
+			name: "confiança fora de 0..1 é limitada",
+			raw:  `{"ranking":[{"candidate":"suporte.md","confidence":1.4},{"candidate":"geral.md","confidence":-0.3}]}`,
+			wantRanking: []RouteScore{
+				{Candidate: "suporte.md", Score: 1},
+				{Candidate: "geral.md", Score: 0},
+			},
+		},
+		{
+			name:         "pergunta de esclarecimento",
+			raw:          `{"ranking":[],"clarifying_question":"  Boleto ou cartão?  "}`,
+			wantQuestion: "Boleto ou cartão?",
+		},
+		{
+			name:        "texto com só o nome do arquivo",
+			raw:         "suporte.md\n",
+			wantRanking: []RouteScore{{Candidate: "suporte.md", Score: 1}},
+		},
+		{
+			name: "texto sem candidato",
+			raw:  "não sei",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranking, question := parseRouterReply(tt.raw, candidates)
+			if !reflect.DeepEqual(ranking, tt.wantRanking) {
+				t.Errorf("ranking = %+v, quer %+v", ranking, tt.wantRanking)
+			}
+			if question != tt.wantQuestion {
+				t.Errorf("pergunta = %q, quer %q", question, tt.wantQuestion)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package agent
+
+import (
+	"log/slog"
+
+	"github.com/RafaelZelak/agentkit/internal/memory"
+	"github.com/RafaelZelak/agentkit/internal/openai"
+	"github.com/RafaelZelak/agentkit/internal/tokenizer"
+	"github.com/RafaelZelak/agentkit/internal/tools"
+)
+
+// Runtime agrupa as dependências de um agent. Cada agentkit.Agent monta o seu,
+// então vários agents no mesmo processo não compartilham tools nem memória.
+type Runtime struct {
+	LLM    openai.Provider
+	Memory memory.Store
+	Tools  *tools.Catalog
+
+	// Tokenizer conta os tokens do contexto; nil usa a estimativa sem vocabulário.
+	Tokenizer tokenizer.Tokenizer
+
+	// Logger recebe os avisos do agent (ex.: tool recusada); nil usa slog.Default().
+	Logger *slog.Logger
+
+	embeddings embeddingCache
+}
+
+func (rt *Runtime) logger() *slog.Logger {
+	if rt.Logger != nil {
+		return rt.Logger
+	}
+	return slog.Default()
+}
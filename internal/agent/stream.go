@@ -0,0 +1,159 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/RafaelZelak/agentkit/internal/openai"
+)
+
+// StreamEvent é o evento entregue por RunStream.
+//
+//   - "delta": pedaço do texto da resposta (Delta)
+//   - "discard": o texto entregue desde o último "tool" era de uma resposta
+//     intermediária, que terminou pedindo tools; descarte-o
+//   - "tool": tool executada no meio do loop (Tool, Args, Output)
+//   - "done": resposta final já persistida na memória (Text, Result)
+//   - "error": falha; o canal é fechado em seguida (Err)
+type StreamEvent struct {
+	Type   string
+	Delta  string
+	Tool   string
+	Args   []string
+	Output string
+	Text   string
+	Result *Result
+	Err    error
+}
+
+// RunStream faz o mesmo que Run, mas entrega o texto conforme o modelo gera.
+// No modo legacy não dá para saber se a resposta é uma linha TOOL: antes dela
+// terminar, então o texto final chega em um único delta.
+func RunStream(
+	ctx context.Context,
+	rt *Runtime,
+	model string,
+	embeddingModel string,
+	sessionID string,
+	promptPath string,
+	userMessage string,
+	opts ...Option,
+) (<-chan StreamEvent, error) {
+	cancel := func() {}
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		ctx, cancel = context.WithTimeout(ctx, 60*time.Second)
+	}
+
+	st, err := prepareRun(ctx, rt, model, embeddingModel, sessionID, promptPath, userMessage, opts)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	ch := make(chan StreamEvent, 16)
+	emit := func(ev StreamEvent) {
+		select {
+		case ch <- ev:
+		case <-ctx.Done():
+		}
+	}
+
+	go func() {
+		err := streamRun(ctx, rt, st, embeddingModel, sessionID, userMessage, emit)
+		if err != nil {
+			emit(StreamEvent{Type: "error", Err: err})
+		}
+		close(ch)
+		cancel()
+	}()
+
+	return ch, nil
+}
+
+func streamRun(
+	ctx context.Context,
+	rt *Runtime,
+	st *runState,
+	embeddingModel string,
+	sessionID string,
+	userMessage string,
+	emit func(StreamEvent),
+) error {
+	res := &Result{BasePrompt: st.promptPath}
+	respond := res.track(streamRespond(rt.LLM, st.legacy, emit))
+	onStep := func(tc ToolCall) {
+		emit(StreamEvent{Type: "tool", Tool: tc.Name, Args: tc.Args, Output: tc.Output})
+	}
+
+	req := st.newReq()
+	resp, err := respond(ctx, req)
+	if err != nil {
+		return err
+	}
+	resp, calls, stop, err := runToolLoop(ctx, rt, respond, st.legacy, st.limits, st.newReq, req, resp, userMessage, onStep)
+	if err != nil {
+		return err
+	}
+
+	res.Text = strings.TrimSpace(resp.OutputText)
+	res.ToolCalls = calls
+	res.StopReason = stop
+	res.Model = st.respModel
+	res.Context = st.context
+	res.Context.addToolOutputs(st.limits.asm, calls)
+	if st.legacy {
+		emit(StreamEvent{Type: "delta", Delta: res.Text})
+	}
+	if err := st.persist(rt, embeddingModel, sessionID, userMessage, res, resp); err != nil {
+		return err
+	}
+	// o texto já foi todo entregue; o resumo roda antes do "done" para que o
+	// Result traga o uso dele, como em Run.
+	st.summarize(rt, sessionID, res)
+	emit(StreamEvent{Type: "done", Text: res.Text, Result: res})
+	return nil
+}
+
+func streamRespond(cli openai.Provider, legacy bool, emit func(StreamEvent)) respondFunc {
+	sp, ok := cli.(openai.StreamProvider)
+	if !ok {
+		return func(ctx context.Context, req *openai.ResponsesRequest) (*openai.ResponseEnvelope, error) {
+			resp, err := cli.Respond(ctx, req)
+			if err == nil && !legacy && resp.OutputText != "" && len(resp.ToolCalls) == 0 {
+				emit(StreamEvent{Type: "delta", Delta: resp.OutputText})
+			}
+			return resp, err
+		}
+	}
+	return func(ctx context.Context, req *openai.ResponsesRequest) (*openai.ResponseEnvelope, error) {
+		events, err := sp.RespondStream(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		var resp *openai.ResponseEnvelope
+		streamed := false
+		for ev := range events {
+			switch {
+			case ev.Err != nil:
+				return nil, ev.Err
+			case ev.Delta != "" && !legacy:
+				streamed = true
+				emit(StreamEvent{Type: "delta", Delta: ev.Delta})
+			case ev.Response != nil:
+				resp = ev.Response
+			}
+		}
+		if resp == nil {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			return nil, errors.New("stream closed without a response")
+		}
+		if streamed && len(resp.ToolCalls) > 0 {
+			emit(StreamEvent{Type: "discard"})
+		}
+		return resp, nil
+	}
+}
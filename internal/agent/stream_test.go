@@ -0,0 +1,101 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/RafaelZelak/agentkit/internal/memory"
+	"github.com/RafaelZelak/agentkit/internal/openai"
+)
+
+// scriptedStream devolve uma resposta por chamada de RespondStream e usa
+// Respond só para o resumo.
+type scriptedStream struct {
+	streams [][]openai.StreamEvent
+	summary *openai.ResponseEnvelope
+}
+
+func (s *scriptedStream) Embed(context.Context, string, string) ([]float32, error) {
+	return []float32{1, 0}, nil
+}
+
+func (s *scriptedStream) Respond(context.Context, *openai.ResponsesRequest) (*openai.ResponseEnvelope, error) {
+	return s.summary, nil
+}
+
+func (s *scriptedStream) RespondStream(context.Context, *openai.ResponsesRequest) (<-chan openai.StreamEvent, error) {
+	evs := s.streams[0]
+	s.streams = s.streams[1:]
+	ch := make(chan openai.StreamEvent, len(evs))
+	for _, ev := range evs {
+		ch <- ev
+	}
+	close(ch)
+	return ch, nil
+}
+
+func TestRunStreamDiscardsIntermediateTextAndCountsSummary(t *testing.T) {
+	ctx := context.Background()
+	prompt := filepath.Join(t.TempDir(), "base.md")
+	if err := os.WriteFile(prompt, []byte("Você é um assistente."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mem := memory.NewInMemoryStore()
+	for _, text := range []string{"a", "b", "c", "d", "e", "f"} {
+		if _, err := mem.SaveEmbeddedMessage(ctx, "s1", "user", text, []float32{0, 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	llm := &scriptedStream{
+		streams: [][]openai.StreamEvent{
+			{
+				{Delta: "Vou verificar"},
+				{Response: &openai.ResponseEnvelope{
+					OutputText: "Vou verificar",
+					ToolCalls:  []openai.FunctionCall{{CallID: "c1", Name: "busca", Arguments: "{}"}},
+					Usage:      openai.Usage{OutputTokens: 3},
+				}},
+			},
+			{
+				{Delta: "Pronto"},
+				{Response: &openai.ResponseEnvelope{OutputText: "Pronto", Usage: openai.Usage{OutputTokens: 2}}},
+			},
+		},
+		summary: &openai.ResponseEnvelope{OutputText: "resumo", Usage: openai.Usage{OutputTokens: 10}},
+	}
+	rt := &Runtime{LLM: llm, Memory: mem}
+
+	events, err := RunStream(ctx, rt, "m", "e", "s1", prompt, "oi", WithSummaryThreshold(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	var done *Result
+	for ev := range events {
+		if ev.Err != nil {
+			t.Fatal(ev.Err)
+		}
+		types = append(types, ev.Type)
+		if ev.Type == "done" {
+			done = ev.Result
+		}
+	}
+
+	want := []string{"delta", "discard", "tool", "delta", "done"}
+	if len(types) != len(want) {
+		t.Fatalf("eventos = %v, quer %v", types, want)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Fatalf("eventos = %v, quer %v", types, want)
+		}
+	}
+	if done.Text != "Pronto" {
+		t.Errorf("Text = %q", done.Text)
+	}
+	if !done.SummaryUpdated || done.Usage.OutputTokens != 15 {
+		t.Errorf("SummaryUpdated = %v, OutputTokens = %d; quer true e 15", done.SummaryUpdated, done.Usage.OutputTokens)
+	}
+}
@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"context"
+	"strings"
+
+	"github.com/RafaelZelak/agentkit/internal/memory"
+	"github.com/RafaelZelak/agentkit/internal/openai"
+)
+
+const summaryPrompt = `Você mantém o resumo de uma conversa de atendimento.
+Atualize o resumo atual incorporando as novas mensagens. Preserve nomes, números,
+identificadores, pedidos do usuário, pendências e decisões já tomadas; descarte
+cumprimentos e repetições. Responda apenas com o resumo atualizado, em texto
+corrido, com no máximo 250 palavras.`
+
+// maxSummaryBatch limita quantas mensagens entram em uma única atualização.
+const maxSummaryBatch = 200
+
+// maybeSummarize condensa as mensagens antigas da sessão quando, fora da janela
+// de memória curta (keep), já existem threshold mensagens ainda não resumidas.
+// Devolve true quando o resumo foi atualizado.
+func maybeSummarize(
+	ctx context.Context,
+	mem memory.Store,
+	respond respondFunc,
+	model string,
+	sessionID string,
+	keep int,
+	threshold int,
+) (bool, error) {
+	if threshold <= 0 {
+		return false, nil
+	}
+
+	current, err := mem.LoadSummary(ctx, sessionID)
+	if err != nil {
+		return false, err
+	}
+	var upTo int64
+	if current != nil {
+		upTo = current.UpToMessageID
+	}
+
+	pending, err := mem.RetrieveSince(ctx, sessionID, upTo, maxSummaryBatch+keep)
+	if err != nil {
+		return false, err
+	}
+	older := len(pending) - keep
+	if older < threshold {
+		return false, nil
+	}
+	batch := pending[:older]
+
+	var sb strings.Builder
+	sb.WriteString("== Resumo atual ==\n")
+	if current != nil && current.Text != "" {
+		sb.WriteString(current.Text)
+	} else {
+		sb.WriteString("(vazio)")
+	}
+	sb.WriteString("\n\n== Novas mensagens ==\n")
+	for _, h := range batch {
+		sb.WriteString(h.Role)
+		sb.WriteString(": ")
+		sb.WriteString(h.Text)
+		sb.WriteByte('\n')
+	}
+
+	req := &openai.ResponsesRequest{
+		Model: model,
+		Input: []openai.Message{
+			{
+				Type:    "message",
+				Role:    "system",
+				Content: []openai.ContentItem{{Type: "input_text", Text: summaryPrompt}},
+			},
+			{
+				Type:    "message",
+				Role:    "user",
+				Content: []openai.ContentItem{{Type: "input_text", Text: sb.String()}},
+			},
+		},
+		MaxOutputTokens: 600,
+	}
+	resp, err := respond(ctx, req)
+	if err != nil {
+		return false, err
+	}
+	text := strings.TrimSpace(resp.OutputText)
+	if text == "" {
+		return false, nil
+	}
+
+	next := memory.Summary{
+		Text:          text,
+		UpToMessageID: batch[len(batch)-1].ID,
+		MessageCount:  len(batch),
+	}
+	if current != nil {
+		next.MessageCount += current.MessageCount
+	}
+	if err := mem.SaveSummary(ctx, sessionID, next); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,49 @@
+package agent
+
+import (
+	"context"
+	"slices"
+
+	"github.com/RafaelZelak/agentkit/internal/openai"
+	"github.com/RafaelZelak/agentkit/internal/tools"
+)
+
+// toolDefs descreve as tools do catálogo para o function calling. allowed nil
+// libera todas.
+func toolDefs(catalog *tools.Catalog, allowed []string) []openai.Tool {
+	all := catalog.All()
+	defs := make([]openai.Tool, 0, len(all))
+	for _, tc := range all {
+		if allowed != nil && !slices.Contains(allowed, tc.Name) {
+			continue
+		}
+		defs = append(defs, openai.Tool{
+			Type:        "function",
+			Name:        tc.Name,
+			Description: tc.Description,
+			Parameters:  tc.Parameters(),
+		})
+	}
+	return defs
+}
+
+func execTool(ctx context.Context, rt *Runtime, tc tools.ToolConfig, args []string, userMessage string) (string, error) {
+	switch tc.Type {
+	case "postgres":
+		return tools.ExecPostgres(ctx, rt.Tools.Pools, tc, tc.QueryArgs(args)...)
+
+	case "postgres_embedding":
+		query := tc.SearchQuery(args)
+		if query == "" {
+			query = userMessage
+		}
+		return tools.ExecPostgresEmbedding(ctx, rt.Tools.Pools, rt.LLM, tc, query, args)
+
+	case "script":
+		return tools.ExecScript(rt.Tools.Scripts, tc, args...)
+
+	case "http":
+		return tools.ExecHTTP(ctx, tc, args...)
+	}
+	return "Tool type não suportado ainda", nil
+}
@@ -1,11 +1,37 @@
 package agent
 
-import "github.com/RafaelZelak/agentkit/internal/openai"
+import (
+	"slices"
+
+	"github.com/RafaelZelak/agentkit/internal/openai"
+)
 
 type builder struct {
 	system         []openai.Message
 	user           openai.ContentItem
 	promptCacheKey string
+	legacyTools    bool
+	maxSteps       int
+	maxToolCalls   int
+
+	summaryThreshold int
+	budget           ContextBudget
+
+	routerMode      string
+	routerTieMargin float64
+	routerThreshold float64
+	routerOnLow     string
+	routerSwitch    float64
+	routerFallback  string
+	routerRetries   int
+	routerOnNoRoute string
+
+	model           string
+	temperature     *float64
+	maxOutputTokens int
+	allowedTools    []string // nil libera todas
+	toolCeiling     []string // tools: do prompt base; nil sem teto
+	strictTools     bool
 }
 
 func newBuilder() *builder {
@@ -14,6 +40,36 @@ func newBuilder() *builder {
 	}
 }
 
+// applyOptions devolve um builder só com as opções aplicadas, para ler configuração.
+func applyOptions(opts []Option) *builder {
+	b := newBuilder()
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// toolAllowlist devolve as tools que o prompt pode usar; nil libera todas. A
+// lista da rota só estreita a do prompt base. No modo estrito um prompt que não
+// declara tools não pode usar nenhuma.
+func (b *builder) toolAllowlist() []string {
+	switch {
+	case b.toolCeiling == nil && b.allowedTools == nil && b.strictTools:
+		return []string{}
+	case b.toolCeiling == nil:
+		return b.allowedTools
+	case b.allowedTools == nil:
+		return b.toolCeiling
+	}
+	out := []string{}
+	for _, name := range b.allowedTools {
+		if slices.Contains(b.toolCeiling, name) {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
 func (b *builder) req(model string) *openai.ResponsesRequest {
 	input := make([]openai.Message, 0, len(b.system)+1)
 	input = append(input, b.system...)
@@ -24,9 +80,14 @@ func (b *builder) req(model string) *openai.ResponsesRequest {
 			b.user,
 		},
 	})
+	if b.model != "" {
+		model = b.model
+	}
 	return &openai.ResponsesRequest{
-		Model:          model,
-		Input:          input,
-		PromptCacheKey: b.promptCacheKey,
+		Model:           model,
+		Input:           input,
+		PromptCacheKey:  b.promptCacheKey,
+		Temperature:     b.temperature,
+		MaxOutputTokens: b.maxOutputTokens,
 	}
 }
@@ -0,0 +1,175 @@
+package ingest
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/RafaelZelak/agentkit/internal/tokenizer"
+)
+
+// Chunk é um trecho de documento: Title é o caminho de títulos da seção
+// ("Guia > Instalação") e Text o conteúdo.
+type Chunk struct {
+	Title string
+	Text  string
+}
+
+type section struct {
+	title string
+	body  string
+}
+
+var headingRe = regexp.MustCompile(`^(#{1,6})\s+(.+?)\s*#*\s*$`)
+
+// splitSections separa markdown pelos títulos, ignorando "#" dentro de blocos
+// de código. Texto sem títulos vira uma seção só.
+func splitSections(text string) []section {
+	var (
+		out    []section
+		stack  []string
+		body   strings.Builder
+		fenced bool
+	)
+	flush := func() {
+		if b := strings.TrimSpace(body.String()); b != "" {
+			out = append(out, section{title: strings.Join(stack, " > "), body: b})
+		}
+		body.Reset()
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			fenced = !fenced
+		}
+		if m := headingRe.FindStringSubmatch(line); m != nil && !fenced {
+			flush()
+			level := len(m[1])
+			if len(stack) >= level {
+				stack = stack[:level-1]
+			}
+			for len(stack) < level-1 {
+				stack = append(stack, "")
+			}
+			stack = append(stack, strings.TrimSpace(m[2]))
+			continue
+		}
+		body.WriteString(line)
+		body.WriteByte('\n')
+	}
+	flush()
+	// níveis pulados (# e depois ###) deixam entradas vazias no caminho
+	for i := range out {
+		parts := strings.Split(out[i].title, " > ")
+		kept := parts[:0]
+		for _, p := range parts {
+			if p != "" {
+				kept = append(kept, p)
+			}
+		}
+		out[i].title = strings.Join(kept, " > ")
+	}
+	return out
+}
+
+var blankLinesRe = regexp.MustCompile(`\n\s*\n`)
+
+// chunker junta parágrafos de uma seção em trechos de até size tokens; cada
+// trecho começa com os últimos overlap tokens do anterior.
+type chunker struct {
+	tok     tokenizer.Tokenizer
+	size    int
+	overlap int
+}
+
+func (c chunker) split(text string, markdown bool) []Chunk {
+	var sections []section
+	if markdown {
+		sections = splitSections(text)
+	} else if t := strings.TrimSpace(text); t != "" {
+		sections = []section{{body: t}}
+	}
+	var out []Chunk
+	for _, s := range sections {
+		for _, t := range c.pack(s.body) {
+			out = append(out, Chunk{Title: s.title, Text: t})
+		}
+	}
+	return out
+}
+
+func (c chunker) pack(body string) []string {
+	var pieces []string
+	for _, p := range blankLinesRe.Split(body, -1) {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		if c.tok.Count(p) <= c.size {
+			pieces = append(pieces, p)
+			continue
+		}
+		pieces = append(pieces, c.splitWords(p)...)
+	}
+
+	var (
+		out []string
+		cur string
+	)
+	for _, p := range pieces {
+		if cur == "" {
+			cur = p
+			continue
+		}
+		if next := cur + "\n\n" + p; c.tok.Count(next) <= c.size {
+			cur = next
+			continue
+		}
+		out = append(out, cur)
+		cur = p
+		if tail := c.tail(out[len(out)-1]); tail != "" {
+			if next := tail + "\n\n" + p; c.tok.Count(next) <= c.size {
+				cur = next
+			}
+		}
+	}
+	if cur != "" {
+		out = append(out, cur)
+	}
+	return out
+}
+
+// splitWords corta um parágrafo maior que size em pedaços de palavras inteiras.
+// Os pedaços deixam espaço para a sobreposição e o separador, senão o pack não
+// conseguiria repetir o fim do trecho anterior.
+func (c chunker) splitWords(p string) []string {
+	limit := c.size
+	if c.overlap > 0 && c.size-c.overlap-1 > 0 {
+		limit = c.size - c.overlap - 1
+	}
+	var (
+		out []string
+		cur []string
+	)
+	for _, w := range strings.Fields(p) {
+		if len(cur) > 0 && c.tok.Count(strings.Join(append(cur, w), " ")) > limit {
+			out = append(out, strings.Join(cur, " "))
+			cur = cur[:0]
+		}
+		cur = append(cur, w)
+	}
+	if len(cur) > 0 {
+		out = append(out, strings.Join(cur, " "))
+	}
+	return out
+}
+
+// tail devolve as últimas palavras de s que cabem em overlap tokens.
+func (c chunker) tail(s string) string {
+	if c.overlap <= 0 {
+		return ""
+	}
+	words := strings.Fields(s)
+	start := len(words)
+	for start > 0 && c.tok.Count(strings.Join(words[start-1:], " ")) <= c.overlap {
+		start--
+	}
+	return strings.Join(words[start:], " ")
+}
@@ -0,0 +1,149 @@
+package ingest
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/RafaelZelak/agentkit/internal/tokenizer"
+)
+
+func TestSplitSections(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []section
+	}{
+		{
+			name: "sem títulos",
+			text: "só texto\n\noutro parágrafo",
+			want: []section{{body: "só texto\n\noutro parágrafo"}},
+		},
+		{
+			name: "caminho de títulos",
+			text: "intro\n# Guia\na\n## Instalação\nb\n## Uso ##\nc\n# Outro\nd",
+			want: []section{
+				{title: "", body: "intro"},
+				{title: "Guia", body: "a"},
+				{title: "Guia > Instalação", body: "b"},
+				{title: "Guia > Uso", body: "c"},
+				{title: "Outro", body: "d"},
+			},
+		},
+		{
+			name: "nível pulado",
+			text: "# A\n### C\ntexto",
+			want: []section{{title: "A > C", body: "texto"}},
+		},
+		{
+			name: "# dentro de bloco de código",
+			text: "# A\n```sh\n# comentário\n```\nfim",
+			want: []section{{title: "A", body: "```sh\n# comentário\n```\nfim"}},
+		},
+		{
+			name: "título sem corpo some",
+			text: "# A\n# B\nb",
+			want: []section{{title: "B", body: "b"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitSections(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, quer %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func numbered(n int) string {
+	w := make([]string, n)
+	for i := range w {
+		w[i] = fmt.Sprintf("w%d", i)
+	}
+	return strings.Join(w, " ")
+}
+
+func TestChunkerSplit(t *testing.T) {
+	tok := tokenizer.Estimator{}
+	tests := []struct {
+		name       string
+		text       string
+		markdown   bool
+		size       int
+		overlap    int
+		wantChunks int // 0: só confere as regras
+		wantTitles []string
+	}{
+		{name: "vazio", text: "  \n", size: 50},
+		{name: "cabe em um trecho", text: "um parágrafo\n\noutro", size: 50, wantChunks: 1},
+		{name: "parágrafo grande é cortado por palavras", text: numbered(200), size: 40},
+		{name: "com sobreposição", text: numbered(200), size: 40, overlap: 10},
+		{
+			name:       "um trecho por seção",
+			text:       "# A\ntexto a\n# B\ntexto b",
+			markdown:   true,
+			size:       50,
+			wantChunks: 2,
+			wantTitles: []string{"A", "B"},
+		},
+		{
+			name:       "markdown em texto puro não separa",
+			text:       "# A\ntexto a\n# B\ntexto b",
+			size:       50,
+			wantChunks: 1,
+			wantTitles: []string{""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := chunker{tok: tok, size: tt.size, overlap: tt.overlap}
+			chunks := c.split(tt.text, tt.markdown)
+			if tt.wantChunks > 0 && len(chunks) != tt.wantChunks {
+				t.Fatalf("%d trechos, quer %d: %+v", len(chunks), tt.wantChunks, chunks)
+			}
+			for i, ch := range chunks {
+				if n := tok.Count(ch.Text); n > tt.size {
+					t.Errorf("trecho %d com %d tokens, acima de %d", i, n, tt.size)
+				}
+				if tt.wantTitles != nil && ch.Title != tt.wantTitles[i] {
+					t.Errorf("trecho %d título %q, quer %q", i, ch.Title, tt.wantTitles[i])
+				}
+			}
+			if strings.TrimSpace(tt.text) == "" && len(chunks) != 0 {
+				t.Errorf("texto vazio gerou %d trechos", len(chunks))
+			}
+		})
+	}
+}
+
+// Sem sobreposição os trechos juntos são o texto original; com ela, cada
+// trecho começa com o fim do anterior.
+func TestChunkerOverlap(t *testing.T) {
+	tok := tokenizer.Estimator{}
+	text := numbered(200)
+
+	plain := chunker{tok: tok, size: 40}.split(text, false)
+	if len(plain) < 2 {
+		t.Fatalf("esperava vários trechos, veio %d", len(plain))
+	}
+	var joined []string
+	for _, ch := range plain {
+		joined = append(joined, ch.Text)
+	}
+	if got := strings.Join(joined, " "); got != text {
+		t.Errorf("trechos sem sobreposição não reconstroem o texto:\n%s", got)
+	}
+
+	c := chunker{tok: tok, size: 40, overlap: 10}
+	over := c.split(text, false)
+	for i := 1; i < len(over); i++ {
+		tail := c.tail(over[i-1].Text)
+		if tail == "" || !strings.HasPrefix(over[i].Text, tail) {
+			t.Errorf("trecho %d não começa com o fim do anterior (%q)", i, tail)
+		}
+		if n := tok.Count(tail); n > 10 {
+			t.Errorf("sobreposição de %d tokens, acima de 10", n)
+		}
+	}
+}
@@ -0,0 +1,379 @@
+// Package ingest popula a tabela de uma tool postgres_embedding a partir de
+// arquivos: lê, divide em trechos, gera os embeddings em lotes e faz upsert,
+// pulando trechos cujo conteúdo não mudou.
+package ingest
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/RafaelZelak/agentkit/internal/openai"
+	"github.com/RafaelZelak/agentkit/internal/pgvec"
+	"github.com/RafaelZelak/agentkit/internal/tokenizer"
+	"github.com/RafaelZelak/agentkit/internal/tools"
+
+	"github.com/lib/pq"
+)
+
+// Options da ingestão. Zero usa o padrão.
+type Options struct {
+	// Tamanho dos trechos e sobreposição entre trechos seguidos, em tokens
+	// (padrão 500 e 50; sobreposição negativa desliga).
+	ChunkTokens   int
+	OverlapTokens int
+	// Textos por requisição de embedding (padrão 64).
+	BatchSize int
+	// Prune apaga da tabela os documentos que não existem mais no diretório.
+	Prune bool
+	// Dim força a dimensão do vetor ao criar a tabela; 0 usa a que o modelo
+	// devolver.
+	Dim int
+}
+
+// Report resume uma ingestão.
+type Report struct {
+	Files     int `json:"files"`
+	Chunks    int `json:"chunks"`
+	Embedded  int `json:"embedded"`
+	Reused    int `json:"reused"`
+	Unchanged int `json:"unchanged"`
+	Deleted   int `json:"deleted"`
+}
+
+// target é a tabela da tool e suas colunas.
+type target struct {
+	table     string
+	content   string
+	embedding string
+	model     string
+}
+
+type chunkRow struct {
+	index int
+	title string
+	text  string
+	hash  string
+	// vec é o vetor no formato do pgvector; unchanged pula a escrita
+	vec       string
+	unchanged bool
+}
+
+type document struct {
+	source string
+	chunks []*chunkRow
+}
+
+// Run lê os arquivos suportados de dir (recursivamente) para a tabela da tool.
+// A fonte de cada trecho é o caminho relativo a dir.
+func Run(ctx context.Context, db *sql.DB, emb openai.Embedder, tok tokenizer.Tokenizer, tc tools.ToolConfig, dir string, opts Options) (*Report, error) {
+	if tc.Type != "postgres_embedding" {
+		return nil, fmt.Errorf("tool %s não é postgres_embedding", tc.Name)
+	}
+	if tc.Table == "" || tc.EmbeddingModel == "" {
+		return nil, fmt.Errorf("tool %s mal configurada: table/embedding_model obrigatórios", tc.Name)
+	}
+	if tok == nil {
+		tok = tokenizer.Estimator{}
+	}
+	t := target{
+		table:     tc.Table,
+		content:   cmp.Or(tc.Column, "content"),
+		embedding: cmp.Or(tc.EmbeddingColumn, "embedding"),
+		model:     tc.EmbeddingModel,
+	}
+	ch := chunker{
+		tok:     tok,
+		size:    cmp.Or(opts.ChunkTokens, 500),
+		overlap: max(cmp.Or(opts.OverlapTokens, 50), 0),
+	}
+
+	docs, err := readDir(dir, ch, t.model)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("nenhum documento suportado em %s", dir)
+	}
+
+	exists, err := tableExists(ctx, db, t.table)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		if err := addColumns(ctx, db, t); err != nil {
+			return nil, err
+		}
+	}
+	rep := &Report{Files: len(docs)}
+	var pending []*chunkRow
+	for _, d := range docs {
+		rep.Chunks += len(d.chunks)
+		if !exists {
+			pending = append(pending, d.chunks...)
+			continue
+		}
+		p, err := diff(ctx, db, t, d, rep)
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, p...)
+	}
+
+	dim, err := embedAll(ctx, emb, t.model, cmp.Or(opts.BatchSize, 64), pending)
+	if err != nil {
+		return nil, err
+	}
+	rep.Embedded = len(pending)
+	if opts.Dim > 0 && dim > 0 && dim != opts.Dim {
+		return nil, fmt.Errorf("o modelo %s devolveu vetores de dimensão %d, mas Dim é %d", t.model, dim, opts.Dim)
+	}
+
+	if !exists {
+		if err := createTable(ctx, db, t, cmp.Or(opts.Dim, dim)); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, d := range docs {
+		n, err := write(ctx, db, t, d)
+		if err != nil {
+			return nil, err
+		}
+		rep.Deleted += n
+	}
+	if opts.Prune {
+		sources := make([]string, len(docs))
+		for i, d := range docs {
+			sources[i] = d.source
+		}
+		res, err := db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE NOT (source = ANY($1))`, t.table), pq.Array(sources))
+		if err != nil {
+			return nil, err
+		}
+		n, _ := res.RowsAffected()
+		rep.Deleted += int(n)
+	}
+	return rep, nil
+}
+
+func readDir(dir string, ch chunker, model string) ([]*document, error) {
+	var docs []*document
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !Supported(path) {
+			return nil
+		}
+		text, markdown, err := readDocument(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		doc := &document{source: filepath.ToSlash(rel)}
+		for i, c := range ch.split(text, markdown) {
+			doc.chunks = append(doc.chunks, &chunkRow{
+				index: i,
+				title: c.Title,
+				text:  c.Text,
+				hash:  contentHash(model, c),
+			})
+		}
+		docs = append(docs, doc)
+		return nil
+	})
+	return docs, err
+}
+
+// contentHash inclui o modelo: trocar de modelo gera embeddings novos.
+func contentHash(model string, c Chunk) string {
+	sum := sha256.Sum256([]byte(model + "\x00" + c.Title + "\x00" + c.Text))
+	return hex.EncodeToString(sum[:])
+}
+
+// embedText é o que vai para o embedding: o título da seção ajuda a busca.
+func (c *chunkRow) embedText() string {
+	if c.title == "" {
+		return c.text
+	}
+	return c.title + "\n\n" + c.text
+}
+
+func tableExists(ctx context.Context, db *sql.DB, table string) (bool, error) {
+	var ok bool
+	err := db.QueryRowContext(ctx, `SELECT to_regclass($1) IS NOT NULL`, table).Scan(&ok)
+	return ok, err
+}
+
+// diff marca os trechos iguais ao que já está na mesma posição e reaproveita o
+// vetor de trechos com o mesmo hash em qualquer lugar da tabela. Devolve os que
+// precisam de embedding.
+func diff(ctx context.Context, db *sql.DB, t target, d *document, rep *Report) ([]*chunkRow, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(`SELECT chunk, content_hash FROM %s WHERE source = $1`, t.table), d.source)
+	if err != nil {
+		return nil, err
+	}
+	stored := map[int]string{}
+	for rows.Next() {
+		var (
+			i int
+			h string
+		)
+		if err := rows.Scan(&i, &h); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		stored[i] = h
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var pending []*chunkRow
+	for _, c := range d.chunks {
+		if stored[c.index] == c.hash {
+			c.unchanged = true
+			rep.Unchanged++
+			continue
+		}
+		var vec sql.NullString
+		err := db.QueryRowContext(ctx, fmt.Sprintf(`SELECT %s::text FROM %s WHERE content_hash = $1 AND %s IS NOT NULL LIMIT 1`, t.embedding, t.table, t.embedding), c.hash).Scan(&vec)
+		switch {
+		case err == nil && vec.Valid:
+			c.vec = vec.String
+			rep.Reused++
+		case err == nil || errors.Is(err, sql.ErrNoRows):
+			pending = append(pending, c)
+		default:
+			return nil, err
+		}
+	}
+	return pending, nil
+}
+
+// embedAll gera os vetores em lotes e devolve a dimensão deles.
+func embedAll(ctx context.Context, emb openai.Embedder, model string, batch int, chunks []*chunkRow) (dim int, err error) {
+	be, isBatch := emb.(openai.BatchEmbedder)
+	for start := 0; start < len(chunks); start += batch {
+		part := chunks[start:min(start+batch, len(chunks))]
+		texts := make([]string, len(part))
+		for i, c := range part {
+			texts[i] = c.embedText()
+		}
+		var vecs [][]float32
+		if isBatch {
+			vecs, err = be.EmbedBatch(ctx, model, texts)
+		} else {
+			for _, text := range texts {
+				var v []float32
+				if v, err = emb.Embed(ctx, model, text); err != nil {
+					break
+				}
+				vecs = append(vecs, v)
+			}
+		}
+		if err != nil {
+			return 0, fmt.Errorf("erro ao gerar embeddings: %w", err)
+		}
+		for i, c := range part {
+			c.vec = pgvec.Encode(vecs[i])
+			dim = len(vecs[i])
+		}
+	}
+	return dim, nil
+}
+
+func createTable(ctx context.Context, db *sql.DB, t target, dim int) error {
+	if dim <= 0 {
+		return errors.New("dimensão do embedding desconhecida para criar a tabela")
+	}
+	_, _ = db.ExecContext(ctx, `CREATE EXTENSION IF NOT EXISTS vector`)
+	if _, err := db.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id BIGSERIAL PRIMARY KEY,
+			source TEXT NOT NULL,
+			chunk INT NOT NULL,
+			title TEXT NOT NULL DEFAULT '',
+			%s TEXT NOT NULL,
+			content_hash TEXT NOT NULL,
+			%s vector(%d),
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			UNIQUE (source, chunk)
+		)`, t.table, t.content, t.embedding, dim)); err != nil {
+		return err
+	}
+	_, err := db.ExecContext(ctx, fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s (content_hash)`, indexName(t, "hash"), t.table))
+	return err
+}
+
+// addColumns prepara uma tabela que já existia para a ingestão: cria as
+// colunas de controle e os índices que faltarem. Linhas antigas ficam com
+// source NULL e não são tocadas.
+func addColumns(ctx context.Context, db *sql.DB, t target) error {
+	stmts := []string{
+		fmt.Sprintf(`ALTER TABLE %s
+			ADD COLUMN IF NOT EXISTS source TEXT,
+			ADD COLUMN IF NOT EXISTS chunk INT,
+			ADD COLUMN IF NOT EXISTS title TEXT NOT NULL DEFAULT '',
+			ADD COLUMN IF NOT EXISTS content_hash TEXT,
+			ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now()`, t.table),
+		fmt.Sprintf(`CREATE UNIQUE INDEX IF NOT EXISTS %s ON %s (source, chunk)`, indexName(t, "source_chunk"), t.table),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s (content_hash)`, indexName(t, "hash"), t.table),
+	}
+	for _, q := range stmts {
+		if _, err := db.ExecContext(ctx, q); err != nil {
+			return fmt.Errorf("erro ao preparar a tabela %s para a ingestão: %w", t.table, err)
+		}
+	}
+	return nil
+}
+
+func indexName(t target, suffix string) string {
+	return strings.NewReplacer(".", "_", `"`, "").Replace(t.table) + "_" + suffix + "_idx"
+}
+
+// write grava os trechos novos ou alterados do documento e apaga os que
+// sobraram de uma versão mais longa, em uma transação.
+func write(ctx context.Context, db *sql.DB, t target, d *document) (deleted int, err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	upsert := fmt.Sprintf(`
+		INSERT INTO %[1]s (source, chunk, title, %[2]s, content_hash, %[3]s, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6::vector, now())
+		ON CONFLICT (source, chunk) DO UPDATE SET
+			title = EXCLUDED.title,
+			%[2]s = EXCLUDED.%[2]s,
+			content_hash = EXCLUDED.content_hash,
+			%[3]s = EXCLUDED.%[3]s,
+			updated_at = now()`, t.table, t.content, t.embedding)
+	for _, c := range d.chunks {
+		if c.unchanged {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, upsert, d.source, c.index, c.title, c.text, c.hash, c.vec); err != nil {
+			return 0, err
+		}
+	}
+	res, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE source = $1 AND chunk >= $2`, t.table), d.source, len(d.chunks))
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), tx.Commit()
+}
@@ -0,0 +1,132 @@
+package ingest
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Extensões lidas pela ingestão.
+var readers = map[string]func([]byte) (text string, markdown bool, err error){
+	".md":       readMarkdown,
+	".markdown": readMarkdown,
+	".txt":      readText,
+	".html":     readHTML,
+	".htm":      readHTML,
+	".csv":      readCSV,
+}
+
+// Supported diz se o arquivo tem uma extensão que a ingestão lê.
+func Supported(path string) bool {
+	_, ok := readers[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
+
+// readDocument devolve o texto do arquivo e se ele tem títulos em markdown.
+func readDocument(path string) (string, bool, error) {
+	read, ok := readers[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return "", false, fmt.Errorf("formato não suportado: %s", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, err
+	}
+	text, markdown, err := read(data)
+	if err != nil {
+		return "", false, fmt.Errorf("%s: %w", path, err)
+	}
+	return text, markdown, nil
+}
+
+func readMarkdown(data []byte) (string, bool, error) {
+	return normalizeText(string(data)), true, nil
+}
+
+func readText(data []byte) (string, bool, error) {
+	return normalizeText(string(data)), false, nil
+}
+
+var (
+	htmlDropRes = []*regexp.Regexp{
+		regexp.MustCompile(`(?is)<script\b.*?</script\s*>`),
+		regexp.MustCompile(`(?is)<style\b.*?</style\s*>`),
+		regexp.MustCompile(`(?is)<head\b.*?</head\s*>`),
+		regexp.MustCompile(`(?is)<noscript\b.*?</noscript\s*>`),
+		regexp.MustCompile(`(?s)<!--.*?-->`),
+	}
+	htmlHeadingRe = regexp.MustCompile(`(?is)<h([1-6])\b[^>]*>(.*?)</h[1-6]\s*>`)
+	htmlItemRe    = regexp.MustCompile(`(?i)<li\b[^>]*>`)
+	htmlBlockRe   = regexp.MustCompile(`(?i)</?(p|div|br|tr|ul|ol|table|section|article|blockquote|pre|hr)\b[^>]*>`)
+	htmlTagRe     = regexp.MustCompile(`(?s)<[^>]*>`)
+	spacesRe      = regexp.MustCompile(`[ \t\f\v]+`)
+	manyLinesRe   = regexp.MustCompile(`\n{3,}`)
+)
+
+// readHTML tira as tags e converte h1..h6 em títulos markdown, para a divisão
+// por seções funcionar igual.
+func readHTML(data []byte) (string, bool, error) {
+	s := string(data)
+	for _, re := range htmlDropRes {
+		s = re.ReplaceAllString(s, "")
+	}
+	s = htmlHeadingRe.ReplaceAllStringFunc(s, func(m string) string {
+		sub := htmlHeadingRe.FindStringSubmatch(m)
+		title := strings.Join(strings.Fields(html.UnescapeString(htmlTagRe.ReplaceAllString(sub[2], ""))), " ")
+		return "\n\n" + strings.Repeat("#", int(sub[1][0]-'0')) + " " + title + "\n\n"
+	})
+	s = htmlItemRe.ReplaceAllString(s, "\n- ")
+	s = htmlBlockRe.ReplaceAllString(s, "\n\n")
+	s = htmlTagRe.ReplaceAllString(s, "")
+	s = spacesRe.ReplaceAllString(html.UnescapeString(s), " ")
+	lines := strings.Split(s, "\n")
+	for i, ln := range lines {
+		lines[i] = strings.TrimSpace(ln)
+	}
+	return normalizeText(strings.Join(lines, "\n")), true, nil
+}
+
+// readCSV transforma cada linha em um parágrafo "coluna: valor", então o
+// chunker agrupa linhas inteiras.
+func readCSV(data []byte) (string, bool, error) {
+	r := csv.NewReader(strings.NewReader(strings.TrimPrefix(string(data), "\ufeff")))
+	r.FieldsPerRecord = -1
+	recs, err := r.ReadAll()
+	if err != nil {
+		return "", false, err
+	}
+	if len(recs) < 2 {
+		return "", false, nil
+	}
+	header := recs[0]
+	var sb strings.Builder
+	for _, rec := range recs[1:] {
+		for i, v := range rec {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				continue
+			}
+			name := fmt.Sprintf("coluna %d", i+1)
+			if i < len(header) && strings.TrimSpace(header[i]) != "" {
+				name = strings.TrimSpace(header[i])
+			}
+			sb.WriteString(name + ": " + v + "\n")
+		}
+		sb.WriteString("\n")
+	}
+	return normalizeText(sb.String()), false, nil
+}
+
+func normalizeText(s string) string {
+	s = strings.ReplaceAll(strings.TrimPrefix(s, "\ufeff"), "\r\n", "\n")
+	lines := strings.Split(s, "\n")
+	for i, ln := range lines {
+		lines[i] = strings.TrimRight(ln, " \t")
+	}
+	s = manyLinesRe.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,70 @@
+package ingest
+
+import "testing"
+
+func TestReaders(t *testing.T) {
+	tests := []struct {
+		name         string
+		read         func([]byte) (string, bool, error)
+		in           string
+		want         string
+		wantMarkdown bool
+	}{
+		{
+			name:         "markdown normaliza linhas",
+			read:         readMarkdown,
+			in:           "\ufeff# T\r\n\r\n\r\n\r\ntexto   \r\n",
+			want:         "# T\n\ntexto",
+			wantMarkdown: true,
+		},
+		{
+			name: "texto",
+			read: readText,
+			in:   "a\n\n\n\nb\n",
+			want: "a\n\nb",
+		},
+		{
+			name: "html",
+			read: readHTML,
+			in: `<html><head><title>x</title></head><body>
+				<script>var a = 1;</script><h1>Guia <b>rápido</b></h1>
+				<p>Olá &amp; bem-vindo</p><ul><li>um</li><li>dois</li></ul><!-- nota --></body></html>`,
+			want:         "# Guia rápido\n\nOlá & bem-vindo\n\n- um\n- dois",
+			wantMarkdown: true,
+		},
+		{
+			name: "csv vira coluna: valor",
+			read: readCSV,
+			in:   "\ufeffnome,plano,\nAna,ouro,x\nBia,,\n",
+			want: "nome: Ana\nplano: ouro\ncoluna 3: x\n\nnome: Bia",
+		},
+		{
+			name: "csv só com cabeçalho",
+			read: readCSV,
+			in:   "nome,plano\n",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, markdown, err := tt.read([]byte(tt.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want || markdown != tt.wantMarkdown {
+				t.Errorf("got (%q, %v), quer (%q, %v)", got, markdown, tt.want, tt.wantMarkdown)
+			}
+		})
+	}
+}
+
+func TestSupported(t *testing.T) {
+	for path, want := range map[string]bool{
+		"docs/a.md": true, "A.MD": true, "b.htm": true, "c.csv": true, "d.txt": true,
+		"e.pdf": false, "f": false,
+	} {
+		if got := Supported(path); got != want {
+			t.Errorf("Supported(%q) = %v, quer %v", path, got, want)
+		}
+	}
+}
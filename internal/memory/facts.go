@@ -0,0 +1,31 @@
+package memory
+
+import (
+	"sort"
+	"time"
+)
+
+// Fact é um dado estruturado da sessão extraído da saída de uma tool, por
+// exemplo Category "faturas", Key "123", Field "status", Value "pago". Um fato
+// com mesma categoria, chave e campo substitui o anterior.
+type Fact struct {
+	Category        string    `json:"category"`
+	Key             string    `json:"key"`
+	Field           string    `json:"field"`
+	Value           string    `json:"value"`
+	SourceMessageID int64     `json:"source_message_id,omitempty"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+func sortFacts(facts []Fact) {
+	sort.Slice(facts, func(i, j int) bool {
+		a, b := facts[i], facts[j]
+		if a.Category != b.Category {
+			return a.Category < b.Category
+		}
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		return a.Field < b.Field
+	})
+}
@@ -0,0 +1,202 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+type memMessage struct {
+	id        int64
+	sessionID string
+	role      string
+	text      string
+	embedding []float32
+	createdAt time.Time
+}
+
+type memMetadata struct {
+	messageID int64
+	key       string
+	value     string
+}
+
+// InMemoryStore guarda tudo no processo, sem persistência. A busca semântica é
+// exaustiva por cosseno, adequada para testes e sessões pequenas.
+type InMemoryStore struct {
+	mu        sync.RWMutex
+	nextID    int64
+	messages  []memMessage
+	metadata  []memMetadata
+	facts     map[string]map[factID]Fact
+	summaries map[string]Summary
+	routes    map[routeID]SessionRoute
+}
+
+type routeID struct {
+	sessionID, routerPath string
+}
+
+type factID struct {
+	category, key, field string
+}
+
+func NewInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{}
+}
+
+func (s *InMemoryStore) SaveEmbeddedMessage(ctx context.Context, sessionID, role, text string, embedding []float32) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	emb := make([]float32, len(embedding))
+	copy(emb, embedding)
+	s.messages = append(s.messages, memMessage{
+		id:        s.nextID,
+		sessionID: sessionID,
+		role:      role,
+		text:      text,
+		embedding: emb,
+		createdAt: time.Now(),
+	})
+	return s.nextID, nil
+}
+
+func (s *InMemoryStore) SaveMetadata(ctx context.Context, messageID int64, key string, value any) error {
+	js, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metadata = append(s.metadata, memMetadata{messageID: messageID, key: key, value: string(js)})
+	return nil
+}
+
+func (s *InMemoryStore) RetrieveRecent(ctx context.Context, sessionID string, depth int) ([]HistoryItem, error) {
+	if depth <= 0 {
+		return nil, nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var rev []HistoryItem
+	for i := len(s.messages) - 1; i >= 0 && len(rev) < depth; i-- {
+		if m := s.messages[i]; m.sessionID == sessionID {
+			rev = append(rev, HistoryItem{ID: m.id, Role: m.role, Text: m.text})
+		}
+	}
+	for i, j := 0, len(rev)-1; i < j; i, j = i+1, j-1 {
+		rev[i], rev[j] = rev[j], rev[i]
+	}
+	return rev, nil
+}
+
+func (s *InMemoryStore) RetrieveSimilar(ctx context.Context, sessionID string, queryEmbedding []float32, topK int) ([]HistoryItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var (
+		items []HistoryItem
+		embs  [][]float32
+	)
+	for _, m := range s.messages {
+		if m.sessionID != sessionID {
+			continue
+		}
+		items = append(items, HistoryItem{ID: m.id, Role: m.role, Text: m.text})
+		embs = append(embs, m.embedding)
+	}
+	return topSimilar(queryEmbedding, items, embs, topK), nil
+}
+
+func (s *InMemoryStore) SaveFacts(ctx context.Context, sessionID string, facts []Fact) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.facts == nil {
+		s.facts = map[string]map[factID]Fact{}
+	}
+	sess := s.facts[sessionID]
+	if sess == nil {
+		sess = map[factID]Fact{}
+		s.facts[sessionID] = sess
+	}
+	now := time.Now()
+	for _, f := range facts {
+		f.UpdatedAt = now
+		sess[factID{f.Category, f.Key, f.Field}] = f
+	}
+	return nil
+}
+
+func (s *InMemoryStore) LoadFacts(ctx context.Context, sessionID string) ([]Fact, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]Fact, 0, len(s.facts[sessionID]))
+	for _, f := range s.facts[sessionID] {
+		out = append(out, f)
+	}
+	sortFacts(out)
+	return out, nil
+}
+
+func (s *InMemoryStore) RetrieveSince(ctx context.Context, sessionID string, afterID int64, limit int) ([]HistoryItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []HistoryItem
+	for _, m := range s.messages {
+		if limit > 0 && len(out) >= limit {
+			break
+		}
+		if m.sessionID == sessionID && m.id > afterID {
+			out = append(out, HistoryItem{ID: m.id, Role: m.role, Text: m.text})
+		}
+	}
+	return out, nil
+}
+
+func (s *InMemoryStore) LoadSummary(ctx context.Context, sessionID string) (*Summary, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sum, ok := s.summaries[sessionID]
+	if !ok {
+		return nil, nil
+	}
+	return &sum, nil
+}
+
+func (s *InMemoryStore) SaveSummary(ctx context.Context, sessionID string, summary Summary) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.summaries == nil {
+		s.summaries = map[string]Summary{}
+	}
+	summary.UpdatedAt = time.Now()
+	s.summaries[sessionID] = summary
+	return nil
+}
+
+func (s *InMemoryStore) LoadRoute(ctx context.Context, sessionID, routerPath string) (*SessionRoute, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.routes[routeID{sessionID, routerPath}]
+	if !ok {
+		return nil, nil
+	}
+	return &r, nil
+}
+
+func (s *InMemoryStore) SaveRoute(ctx context.Context, sessionID string, route SessionRoute) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := routeID{sessionID, route.RouterPath}
+	if route.Prompt == "" {
+		delete(s.routes, id)
+		return nil
+	}
+	if s.routes == nil {
+		s.routes = map[routeID]SessionRoute{}
+	}
+	route.UpdatedAt = time.Now()
+	s.routes[id] = route
+	return nil
+}
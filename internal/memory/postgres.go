@@ -0,0 +1,331 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/RafaelZelak/agentkit/internal/pgvec"
+
+	_ "github.com/lib/pq"
+)
+
+type PostgresStore struct {
+	db           *sql.DB
+	schema       string
+	embeddingDim int
+}
+
+func NewPostgresStore(cfg Config) (*PostgresStore, error) {
+	db, err := sql.Open("postgres", cfg.DSN)
+	if err != nil {
+		return nil, err
+	}
+	s := &PostgresStore{
+		db:           db,
+		schema:       cfg.Schema,
+		embeddingDim: cfg.EmbeddingDim,
+	}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// DB expõe o pool do store para ser reaproveitado (ex.: pelas tools no mesmo DSN).
+func (s *PostgresStore) DB() *sql.DB { return s.db }
+
+func (s *PostgresStore) Close() error { return s.db.Close() }
+
+func (s *PostgresStore) migrate() error {
+	_, _ = s.db.Exec(`CREATE EXTENSION IF NOT EXISTS vector`)
+
+	if _, err := s.db.Exec(fmt.Sprintf(`CREATE SCHEMA IF NOT EXISTS %s;`, pqIdent(s.schema))); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s.chat_memory (
+			id BIGSERIAL PRIMARY KEY,
+			session_id TEXT NOT NULL,
+			role TEXT NOT NULL,
+			text TEXT NOT NULL,
+			embedding vector(%d),
+			created_at TIMESTAMPTZ DEFAULT now()
+		);`, pqIdent(s.schema), s.embeddingDim)); err != nil {
+		return err
+	}
+	_, _ = s.db.Exec(fmt.Sprintf(`CREATE INDEX IF NOT EXISTS chat_memory_session_idx ON %s.chat_memory (session_id)`, pqIdent(s.schema)))
+	_, _ = s.db.Exec(fmt.Sprintf(`CREATE INDEX IF NOT EXISTS chat_memory_embedding_idx ON %s.chat_memory USING ivfflat (embedding vector_cosine_ops) WITH (lists=100)`, pqIdent(s.schema)))
+
+	if _, err := s.db.Exec(fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s.metadata (
+			id BIGSERIAL PRIMARY KEY,
+			message_id BIGINT NOT NULL REFERENCES %s.chat_memory(id) ON DELETE CASCADE,
+			key TEXT NOT NULL,
+			value JSONB NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT now()
+		);`, pqIdent(s.schema), pqIdent(s.schema))); err != nil {
+		return err
+	}
+	_, _ = s.db.Exec(fmt.Sprintf(`CREATE INDEX IF NOT EXISTS metadata_message_idx ON %s.metadata(message_id)`, pqIdent(s.schema)))
+
+	if _, err := s.db.Exec(fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s.facts (
+			id BIGSERIAL PRIMARY KEY,
+			session_id TEXT NOT NULL,
+			category TEXT NOT NULL,
+			fact_key TEXT NOT NULL,
+			field TEXT NOT NULL,
+			value TEXT NOT NULL,
+			source_message_id BIGINT REFERENCES %s.chat_memory(id) ON DELETE SET NULL,
+			created_at TIMESTAMPTZ DEFAULT now(),
+			updated_at TIMESTAMPTZ DEFAULT now(),
+			UNIQUE (session_id, category, fact_key, field)
+		);`, pqIdent(s.schema), pqIdent(s.schema))); err != nil {
+		return err
+	}
+
+	if _, err := s.db.Exec(fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s.summaries (
+			session_id TEXT PRIMARY KEY,
+			summary TEXT NOT NULL,
+			up_to_message_id BIGINT NOT NULL,
+			message_count INT NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT now(),
+			updated_at TIMESTAMPTZ DEFAULT now()
+		);`, pqIdent(s.schema))); err != nil {
+		return err
+	}
+
+	if _, err := s.db.Exec(fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s.routes (
+			session_id TEXT NOT NULL,
+			router_path TEXT NOT NULL,
+			prompt TEXT NOT NULL,
+			pinned BOOLEAN NOT NULL DEFAULT false,
+			updated_at TIMESTAMPTZ DEFAULT now(),
+			PRIMARY KEY (session_id, router_path)
+		);`, pqIdent(s.schema))); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *PostgresStore) SaveEmbeddedMessage(ctx context.Context, sessionID, role, text string, embedding []float32) (int64, error) {
+	var id int64
+	if len(embedding) == 0 {
+		err := s.db.QueryRowContext(ctx,
+			fmt.Sprintf(`INSERT INTO %s.chat_memory (session_id, role, text, embedding)
+			 VALUES ($1,$2,$3,NULL) RETURNING id`, pqIdent(s.schema)),
+			sessionID, role, text,
+		).Scan(&id)
+		return id, err
+	}
+	vec := pgvec.Encode(embedding)
+	err := s.db.QueryRowContext(ctx,
+		fmt.Sprintf(`INSERT INTO %s.chat_memory (session_id, role, text, embedding)
+		 VALUES ($1,$2,$3,$4::vector) RETURNING id`, pqIdent(s.schema)),
+		sessionID, role, text, vec,
+	).Scan(&id)
+	return id, err
+}
+
+func (s *PostgresStore) SaveMetadata(ctx context.Context, messageID int64, key string, value any) error {
+	js, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx,
+		fmt.Sprintf(`INSERT INTO %s.metadata (message_id, key, value) VALUES ($1,$2,$3::jsonb)`, pqIdent(s.schema)),
+		messageID, key, string(js),
+	)
+	return err
+}
+
+func (s *PostgresStore) RetrieveSimilar(ctx context.Context, sessionID string, queryEmbedding []float32, topK int) ([]HistoryItem, error) {
+	if topK <= 0 {
+		topK = 5
+	}
+	vec := pgvec.Encode(queryEmbedding)
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT id, role, text
+		FROM %s.chat_memory
+		WHERE session_id=$1 AND embedding IS NOT NULL
+		ORDER BY embedding <=> $2::vector
+		LIMIT $3
+	`, pqIdent(s.schema)), sessionID, vec, topK)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []HistoryItem
+	for rows.Next() {
+		var h HistoryItem
+		if err := rows.Scan(&h.ID, &h.Role, &h.Text); err == nil {
+			out = append(out, h)
+		}
+	}
+	return out, nil
+}
+
+func (s *PostgresStore) RetrieveRecent(ctx context.Context, sessionID string, depth int) ([]HistoryItem, error) {
+	if depth <= 0 {
+		return nil, nil
+	}
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT id, role, text
+		FROM %s.chat_memory
+		WHERE session_id=$1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2
+	`, pqIdent(s.schema)), sessionID, depth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rev []HistoryItem
+	for rows.Next() {
+		var h HistoryItem
+		if err := rows.Scan(&h.ID, &h.Role, &h.Text); err == nil {
+			rev = append(rev, h)
+		}
+	}
+	for i, j := 0, len(rev)-1; i < j; i, j = i+1, j-1 {
+		rev[i], rev[j] = rev[j], rev[i]
+	}
+	return rev, nil
+}
+
+func (s *PostgresStore) SaveFacts(ctx context.Context, sessionID string, facts []Fact) error {
+	for _, f := range facts {
+		if _, err := s.db.ExecContext(ctx, fmt.Sprintf(`
+			INSERT INTO %s.facts (session_id, category, fact_key, field, value, source_message_id)
+			VALUES ($1,$2,$3,$4,$5,$6)
+			ON CONFLICT (session_id, category, fact_key, field)
+			DO UPDATE SET value = EXCLUDED.value, source_message_id = EXCLUDED.source_message_id, updated_at = now()
+		`, pqIdent(s.schema)), sessionID, f.Category, f.Key, f.Field, f.Value, nullID(f.SourceMessageID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *PostgresStore) LoadFacts(ctx context.Context, sessionID string) ([]Fact, error) {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT category, fact_key, field, value, COALESCE(source_message_id, 0), updated_at
+		FROM %s.facts
+		WHERE session_id = $1
+		ORDER BY category, fact_key, field
+	`, pqIdent(s.schema)), sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Fact
+	for rows.Next() {
+		var f Fact
+		if err := rows.Scan(&f.Category, &f.Key, &f.Field, &f.Value, &f.SourceMessageID, &f.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, f)
+	}
+	return out, rows.Err()
+}
+
+func (s *PostgresStore) RetrieveSince(ctx context.Context, sessionID string, afterID int64, limit int) ([]HistoryItem, error) {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT id, role, text
+		FROM %s.chat_memory
+		WHERE session_id=$1 AND id > $2
+		ORDER BY id ASC
+		LIMIT $3
+	`, pqIdent(s.schema)), sessionID, afterID, sql.NullInt64{Int64: int64(limit), Valid: limit > 0}) // LIMIT NULL é sem limite
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []HistoryItem
+	for rows.Next() {
+		var h HistoryItem
+		if err := rows.Scan(&h.ID, &h.Role, &h.Text); err == nil {
+			out = append(out, h)
+		}
+	}
+	return out, nil
+}
+
+func (s *PostgresStore) LoadSummary(ctx context.Context, sessionID string) (*Summary, error) {
+	var sum Summary
+	err := s.db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT summary, up_to_message_id, message_count, updated_at
+		FROM %s.summaries
+		WHERE session_id=$1
+	`, pqIdent(s.schema)), sessionID).Scan(&sum.Text, &sum.UpToMessageID, &sum.MessageCount, &sum.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sum, nil
+}
+
+func (s *PostgresStore) SaveSummary(ctx context.Context, sessionID string, summary Summary) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s.summaries (session_id, summary, up_to_message_id, message_count)
+		VALUES ($1,$2,$3,$4)
+		ON CONFLICT (session_id)
+		DO UPDATE SET summary = EXCLUDED.summary, up_to_message_id = EXCLUDED.up_to_message_id,
+			message_count = EXCLUDED.message_count, updated_at = now()
+	`, pqIdent(s.schema)), sessionID, summary.Text, summary.UpToMessageID, summary.MessageCount)
+	return err
+}
+
+func (s *PostgresStore) LoadRoute(ctx context.Context, sessionID, routerPath string) (*SessionRoute, error) {
+	r := SessionRoute{RouterPath: routerPath}
+	err := s.db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT prompt, pinned, updated_at
+		FROM %s.routes
+		WHERE session_id=$1 AND router_path=$2
+	`, pqIdent(s.schema)), sessionID, routerPath).Scan(&r.Prompt, &r.Pinned, &r.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (s *PostgresStore) SaveRoute(ctx context.Context, sessionID string, route SessionRoute) error {
+	if route.Prompt == "" {
+		_, err := s.db.ExecContext(ctx, fmt.Sprintf(`
+			DELETE FROM %s.routes WHERE session_id=$1 AND router_path=$2
+		`, pqIdent(s.schema)), sessionID, route.RouterPath)
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s.routes (session_id, router_path, prompt, pinned)
+		VALUES ($1,$2,$3,$4)
+		ON CONFLICT (session_id, router_path)
+		DO UPDATE SET prompt = EXCLUDED.prompt, pinned = EXCLUDED.pinned, updated_at = now()
+	`, pqIdent(s.schema)), sessionID, route.RouterPath, route.Prompt, route.Pinned)
+	return err
+}
+
+func nullID(id int64) any {
+	if id <= 0 {
+		return nil
+	}
+	return id
+}
+
+func pqIdent(s string) string {
+	return s
+}
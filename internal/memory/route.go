@@ -0,0 +1,13 @@
+package memory
+
+import "time"
+
+// SessionRoute é a rota atual de uma sessão para um router. Prompt é o arquivo
+// escolhido, relativo ao diretório do router. Pinned indica uma rota fixada
+// pela aplicação, que o router não troca.
+type SessionRoute struct {
+	RouterPath string    `json:"router_path"`
+	Prompt     string    `json:"prompt"`
+	Pinned     bool      `json:"pinned,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
@@ -0,0 +1,336 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+)
+
+// SQLiteStore persiste a memória em um arquivo SQLite. Os embeddings ficam em
+// BLOB e a busca semântica é feita no processo, por cosseno.
+//
+// O driver não é importado pela lib: registre um no binário, por exemplo
+// `import _ "modernc.org/sqlite"` (driver "sqlite", padrão) ou
+// `import _ "github.com/mattn/go-sqlite3"` com SQLiteDriver "sqlite3".
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteStore(cfg Config) (*SQLiteStore, error) {
+	if cfg.SQLitePath == "" {
+		return nil, errors.New("sqlite memory: SQLitePath obrigatório")
+	}
+	driver := cfg.SQLiteDriver
+	if driver == "" {
+		driver = "sqlite"
+	}
+	db, err := sql.Open(driver, cfg.SQLitePath)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite serializa escritas; uma conexão evita "database is locked"
+	db.SetMaxOpenConns(1)
+
+	s := &SQLiteStore{db: db}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *SQLiteStore) Close() error { return s.db.Close() }
+
+func (s *SQLiteStore) migrate() error {
+	if _, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS chat_memory (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			session_id TEXT NOT NULL,
+			role TEXT NOT NULL,
+			text TEXT NOT NULL,
+			embedding BLOB,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`); err != nil {
+		return err
+	}
+	_, _ = s.db.Exec(`CREATE INDEX IF NOT EXISTS chat_memory_session_idx ON chat_memory (session_id)`)
+
+	if _, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS metadata (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			message_id INTEGER NOT NULL REFERENCES chat_memory(id) ON DELETE CASCADE,
+			key TEXT NOT NULL,
+			value TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`); err != nil {
+		return err
+	}
+	_, _ = s.db.Exec(`CREATE INDEX IF NOT EXISTS metadata_message_idx ON metadata (message_id)`)
+
+	if _, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS facts (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			session_id TEXT NOT NULL,
+			category TEXT NOT NULL,
+			fact_key TEXT NOT NULL,
+			field TEXT NOT NULL,
+			value TEXT NOT NULL,
+			source_message_id INTEGER REFERENCES chat_memory(id) ON DELETE SET NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (session_id, category, fact_key, field)
+		)`); err != nil {
+		return err
+	}
+
+	if _, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS summaries (
+			session_id TEXT PRIMARY KEY,
+			summary TEXT NOT NULL,
+			up_to_message_id INTEGER NOT NULL,
+			message_count INTEGER NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`); err != nil {
+		return err
+	}
+
+	if _, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS routes (
+			session_id TEXT NOT NULL,
+			router_path TEXT NOT NULL,
+			prompt TEXT NOT NULL,
+			pinned INTEGER NOT NULL DEFAULT 0,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (session_id, router_path)
+		)`); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *SQLiteStore) SaveEmbeddedMessage(ctx context.Context, sessionID, role, text string, embedding []float32) (int64, error) {
+	var blob any
+	if len(embedding) > 0 {
+		blob = encodeBlob(embedding)
+	}
+	res, err := s.db.ExecContext(ctx,
+		`INSERT INTO chat_memory (session_id, role, text, embedding) VALUES (?,?,?,?)`,
+		sessionID, role, text, blob,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (s *SQLiteStore) SaveMetadata(ctx context.Context, messageID int64, key string, value any) error {
+	js, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO metadata (message_id, key, value) VALUES (?,?,?)`,
+		messageID, key, string(js),
+	)
+	return err
+}
+
+func (s *SQLiteStore) RetrieveRecent(ctx context.Context, sessionID string, depth int) ([]HistoryItem, error) {
+	if depth <= 0 {
+		return nil, nil
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, role, text
+		FROM chat_memory
+		WHERE session_id=?
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`, sessionID, depth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rev []HistoryItem
+	for rows.Next() {
+		var h HistoryItem
+		if err := rows.Scan(&h.ID, &h.Role, &h.Text); err == nil {
+			rev = append(rev, h)
+		}
+	}
+	for i, j := 0, len(rev)-1; i < j; i, j = i+1, j-1 {
+		rev[i], rev[j] = rev[j], rev[i]
+	}
+	return rev, nil
+}
+
+func (s *SQLiteStore) RetrieveSimilar(ctx context.Context, sessionID string, queryEmbedding []float32, topK int) ([]HistoryItem, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, role, text, embedding
+		FROM chat_memory
+		WHERE session_id=? AND embedding IS NOT NULL
+	`, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		items []HistoryItem
+		embs  [][]float32
+	)
+	for rows.Next() {
+		var (
+			h    HistoryItem
+			blob []byte
+		)
+		if err := rows.Scan(&h.ID, &h.Role, &h.Text, &blob); err != nil {
+			continue
+		}
+		items = append(items, h)
+		embs = append(embs, decodeBlob(blob))
+	}
+	return topSimilar(queryEmbedding, items, embs, topK), nil
+}
+
+func (s *SQLiteStore) SaveFacts(ctx context.Context, sessionID string, facts []Fact) error {
+	for _, f := range facts {
+		if _, err := s.db.ExecContext(ctx, `
+			INSERT INTO facts (session_id, category, fact_key, field, value, source_message_id)
+			VALUES (?,?,?,?,?,?)
+			ON CONFLICT (session_id, category, fact_key, field)
+			DO UPDATE SET value = excluded.value, source_message_id = excluded.source_message_id, updated_at = CURRENT_TIMESTAMP
+		`, sessionID, f.Category, f.Key, f.Field, f.Value, nullID(f.SourceMessageID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteStore) LoadFacts(ctx context.Context, sessionID string) ([]Fact, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT category, fact_key, field, value, COALESCE(source_message_id, 0), updated_at
+		FROM facts
+		WHERE session_id = ?
+		ORDER BY category, fact_key, field
+	`, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Fact
+	for rows.Next() {
+		var f Fact
+		if err := rows.Scan(&f.Category, &f.Key, &f.Field, &f.Value, &f.SourceMessageID, &f.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, f)
+	}
+	return out, rows.Err()
+}
+
+func (s *SQLiteStore) RetrieveSince(ctx context.Context, sessionID string, afterID int64, limit int) ([]HistoryItem, error) {
+	if limit <= 0 {
+		limit = -1 // no SQLite, LIMIT negativo é sem limite
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, role, text
+		FROM chat_memory
+		WHERE session_id=? AND id > ?
+		ORDER BY id ASC
+		LIMIT ?
+	`, sessionID, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []HistoryItem
+	for rows.Next() {
+		var h HistoryItem
+		if err := rows.Scan(&h.ID, &h.Role, &h.Text); err == nil {
+			out = append(out, h)
+		}
+	}
+	return out, nil
+}
+
+func (s *SQLiteStore) LoadSummary(ctx context.Context, sessionID string) (*Summary, error) {
+	var sum Summary
+	err := s.db.QueryRowContext(ctx, `
+		SELECT summary, up_to_message_id, message_count, updated_at
+		FROM summaries
+		WHERE session_id=?
+	`, sessionID).Scan(&sum.Text, &sum.UpToMessageID, &sum.MessageCount, &sum.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sum, nil
+}
+
+func (s *SQLiteStore) SaveSummary(ctx context.Context, sessionID string, summary Summary) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO summaries (session_id, summary, up_to_message_id, message_count)
+		VALUES (?,?,?,?)
+		ON CONFLICT (session_id)
+		DO UPDATE SET summary = excluded.summary, up_to_message_id = excluded.up_to_message_id,
+			message_count = excluded.message_count, updated_at = CURRENT_TIMESTAMP
+	`, sessionID, summary.Text, summary.UpToMessageID, summary.MessageCount)
+	return err
+}
+
+func (s *SQLiteStore) LoadRoute(ctx context.Context, sessionID, routerPath string) (*SessionRoute, error) {
+	r := SessionRoute{RouterPath: routerPath}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT prompt, pinned, updated_at
+		FROM routes
+		WHERE session_id=? AND router_path=?
+	`, sessionID, routerPath).Scan(&r.Prompt, &r.Pinned, &r.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (s *SQLiteStore) SaveRoute(ctx context.Context, sessionID string, route SessionRoute) error {
+	if route.Prompt == "" {
+		_, err := s.db.ExecContext(ctx, `
+			DELETE FROM routes WHERE session_id=? AND router_path=?
+		`, sessionID, route.RouterPath)
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO routes (session_id, router_path, prompt, pinned)
+		VALUES (?,?,?,?)
+		ON CONFLICT (session_id, router_path)
+		DO UPDATE SET prompt = excluded.prompt, pinned = excluded.pinned, updated_at = CURRENT_TIMESTAMP
+	`, sessionID, route.RouterPath, route.Prompt, route.Pinned)
+	return err
+}
+
+func encodeBlob(v []float32) []byte {
+	buf := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(x))
+	}
+	return buf
+}
+
+func decodeBlob(b []byte) []float32 {
+	out := make([]float32, len(b)/4)
+	for i := range out {
+		out[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[i*4:]))
+	}
+	return out
+}
@@ -0,0 +1,216 @@
+package memory
+
+import (
+	"context"
+	"testing"
+)
+
+// Os testes usam só a interface Store, para valer para qualquer backend; sem
+// Postgres nem driver SQLite no ambiente de testes, rodam com InMemoryStore.
+func TestInMemoryStore(t *testing.T) {
+	testStore(t, func() Store { return NewInMemoryStore() })
+}
+
+func testStore(t *testing.T, newStore func() Store) {
+	t.Run("recent", func(t *testing.T) { testRecent(t, newStore()) })
+	t.Run("since", func(t *testing.T) { testSince(t, newStore()) })
+	t.Run("similar", func(t *testing.T) { testSimilar(t, newStore()) })
+	t.Run("facts", func(t *testing.T) { testFacts(t, newStore()) })
+	t.Run("summary", func(t *testing.T) { testSummary(t, newStore()) })
+	t.Run("route", func(t *testing.T) { testRoute(t, newStore()) })
+}
+
+func save(t *testing.T, s Store, sessionID string, texts ...string) []int64 {
+	t.Helper()
+	ids := make([]int64, len(texts))
+	for i, text := range texts {
+		id, err := s.SaveEmbeddedMessage(context.Background(), sessionID, "user", text, []float32{1, float32(i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = id
+	}
+	return ids
+}
+
+func texts(items []HistoryItem) []string {
+	out := make([]string, len(items))
+	for i, it := range items {
+		out[i] = it.Text
+	}
+	return out
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func testRecent(t *testing.T, s Store) {
+	ctx := context.Background()
+	save(t, s, "s1", "a", "b")
+	save(t, s, "s2", "x")
+	save(t, s, "s1", "c", "d")
+
+	tests := []struct {
+		depth int
+		want  []string
+	}{
+		{0, nil},
+		{2, []string{"c", "d"}},
+		{3, []string{"b", "c", "d"}},
+		{10, []string{"a", "b", "c", "d"}},
+	}
+	for _, tt := range tests {
+		got, err := s.RetrieveRecent(ctx, "s1", tt.depth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !equal(texts(got), tt.want) {
+			t.Errorf("RetrieveRecent(%d) = %q, quer %q", tt.depth, texts(got), tt.want)
+		}
+	}
+}
+
+func testSince(t *testing.T, s Store) {
+	ctx := context.Background()
+	ids := save(t, s, "s1", "a", "b", "c", "d")
+	save(t, s, "s2", "x")
+
+	tests := []struct {
+		after int64
+		limit int
+		want  []string
+	}{
+		{0, 0, []string{"a", "b", "c", "d"}},
+		{0, -1, []string{"a", "b", "c", "d"}},
+		{0, 2, []string{"a", "b"}},
+		{ids[1], 0, []string{"c", "d"}},
+		{ids[1], 1, []string{"c"}},
+		{ids[3], 0, nil},
+	}
+	for _, tt := range tests {
+		got, err := s.RetrieveSince(ctx, "s1", tt.after, tt.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !equal(texts(got), tt.want) {
+			t.Errorf("RetrieveSince(%d, %d) = %q, quer %q", tt.after, tt.limit, texts(got), tt.want)
+		}
+	}
+}
+
+func testSimilar(t *testing.T, s Store) {
+	ctx := context.Background()
+	embs := map[string][]float32{
+		"norte":    {0, 1},
+		"leste":    {1, 0},
+		"nordeste": {1, 1},
+		"sul":      {0, -1},
+	}
+	for _, text := range []string{"norte", "leste", "nordeste", "sul"} {
+		if _, err := s.SaveEmbeddedMessage(ctx, "s1", "user", text, embs[text]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.SaveEmbeddedMessage(ctx, "s2", "user", "outra sessão", []float32{0, 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.RetrieveSimilar(ctx, "s1", []float32{0.1, 1}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"norte", "nordeste"}; !equal(texts(got), want) {
+		t.Errorf("RetrieveSimilar = %q, quer %q", texts(got), want)
+	}
+}
+
+func testFacts(t *testing.T, s Store) {
+	ctx := context.Background()
+	err := s.SaveFacts(ctx, "s1", []Fact{
+		{Category: "pedido", Key: "123", Field: "status", Value: "enviado"},
+		{Category: "cliente", Key: "", Field: "nome", Value: "Ana"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a mesma chave (categoria, key, campo) é atualizada, não duplicada
+	err = s.SaveFacts(ctx, "s1", []Fact{{Category: "pedido", Key: "123", Field: "status", Value: "entregue"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.LoadFacts(ctx, "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("LoadFacts = %+v, quer 2 fatos", got)
+	}
+	if got[0].Category != "cliente" || got[1].Value != "entregue" {
+		t.Errorf("LoadFacts = %+v", got)
+	}
+	if got[1].UpdatedAt.IsZero() {
+		t.Error("UpdatedAt não preenchido")
+	}
+
+	other, err := s.LoadFacts(ctx, "s2")
+	if err != nil || len(other) != 0 {
+		t.Errorf("LoadFacts(s2) = %+v, %v", other, err)
+	}
+}
+
+func testSummary(t *testing.T, s Store) {
+	ctx := context.Background()
+	sum, err := s.LoadSummary(ctx, "s1")
+	if err != nil || sum != nil {
+		t.Fatalf("LoadSummary sem resumo = %+v, %v", sum, err)
+	}
+
+	want := Summary{Text: "cliente pediu segunda via", UpToMessageID: 7, MessageCount: 5}
+	if err := s.SaveSummary(ctx, "s1", want); err != nil {
+		t.Fatal(err)
+	}
+	sum, err = s.LoadSummary(ctx, "s1")
+	if err != nil || sum == nil {
+		t.Fatalf("LoadSummary = %+v, %v", sum, err)
+	}
+	if sum.Text != want.Text || sum.UpToMessageID != 7 || sum.MessageCount != 5 || sum.UpdatedAt.IsZero() {
+		t.Errorf("LoadSummary = %+v", sum)
+	}
+}
+
+func testRoute(t *testing.T, s Store) {
+	ctx := context.Background()
+	r, err := s.LoadRoute(ctx, "s1", "router.md")
+	if err != nil || r != nil {
+		t.Fatalf("LoadRoute sem rota = %+v, %v", r, err)
+	}
+
+	if err := s.SaveRoute(ctx, "s1", SessionRoute{RouterPath: "router.md", Prompt: "boleto.md", Pinned: true}); err != nil {
+		t.Fatal(err)
+	}
+	r, err = s.LoadRoute(ctx, "s1", "router.md")
+	if err != nil || r == nil || r.Prompt != "boleto.md" || !r.Pinned {
+		t.Fatalf("LoadRoute = %+v, %v", r, err)
+	}
+	if r, _ := s.LoadRoute(ctx, "s1", "outro.md"); r != nil {
+		t.Errorf("rota vazou para outro router: %+v", r)
+	}
+
+	// Prompt vazio apaga a rota
+	if err := s.SaveRoute(ctx, "s1", SessionRoute{RouterPath: "router.md"}); err != nil {
+		t.Fatal(err)
+	}
+	if r, _ := s.LoadRoute(ctx, "s1", "router.md"); r != nil {
+		t.Errorf("rota não apagada: %+v", r)
+	}
+}
@@ -0,0 +1,12 @@
+package memory
+
+import "time"
+
+// Summary é o resumo incremental de uma sessão. Cobre todas as mensagens com
+// id <= UpToMessageID; as mais novas continuam vindo de RetrieveRecent.
+type Summary struct {
+	Text          string    `json:"text"`
+	UpToMessageID int64     `json:"up_to_message_id"`
+	MessageCount  int       `json:"message_count"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
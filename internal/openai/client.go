@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -16,20 +17,97 @@ type ContentItem struct {
 
 type Message struct {
 	Type    string        `json:"type"`
-	Role    string        `json:"role"`
-	Content []ContentItem `json:"content"`
+	Role    string        `json:"role,omitempty"`
+	Content []ContentItem `json:"content,omitempty"`
+
+	// Para itens function_call / function_call_output
+	CallID    string `json:"call_id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+	Output    string `json:"output,omitempty"`
+}
+
+type Tool struct {
+	Type        string         `json:"type"`
+	Name        string         `json:"name,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters,omitempty"`
 }
 
 type ResponsesRequest struct {
-	Model           string    `json:"model"`
-	Input           []Message `json:"input"`
-	PromptCacheKey  string    `json:"prompt_cache_key,omitempty"`
-	MaxOutputTokens int       `json:"max_output_tokens,omitempty"`
+	Model           string       `json:"model"`
+	Input           []Message    `json:"input"`
+	Tools           []Tool       `json:"tools,omitempty"`
+	ToolChoice      string       `json:"tool_choice,omitempty"`
+	PromptCacheKey  string       `json:"prompt_cache_key,omitempty"`
+	Temperature     *float64     `json:"temperature,omitempty"`
+	MaxOutputTokens int          `json:"max_output_tokens,omitempty"`
+	Text            *TextOptions `json:"text,omitempty"`
+	Stream          bool         `json:"stream,omitempty"`
+}
+
+// TextOptions controla o formato da saída de texto.
+type TextOptions struct {
+	Format *TextFormat `json:"format,omitempty"`
+}
+
+// TextFormat com Type "json_schema" pede saída estruturada validada por Schema.
+type TextFormat struct {
+	Type   string         `json:"type"`
+	Name   string         `json:"name,omitempty"`
+	Schema map[string]any `json:"schema,omitempty"`
+	Strict bool           `json:"strict,omitempty"`
+}
+
+// JSONSchemaFormat monta o formato de saída estruturada com schema estrito.
+func JSONSchemaFormat(name string, schema map[string]any) *TextOptions {
+	return &TextOptions{Format: &TextFormat{Type: "json_schema", Name: name, Schema: schema, Strict: true}}
+}
+
+type FunctionCall struct {
+	ID        string `json:"id,omitempty"`
+	CallID    string `json:"call_id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// Item devolve a chamada no formato de input, para reenviar ao modelo junto do output.
+func (fc FunctionCall) Item() Message {
+	return Message{
+		Type:      "function_call",
+		CallID:    fc.CallID,
+		Name:      fc.Name,
+		Arguments: fc.Arguments,
+	}
+}
+
+func FunctionCallOutput(callID, output string) Message {
+	return Message{
+		Type:   "function_call_output",
+		CallID: callID,
+		Output: output,
+	}
+}
+
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	CachedTokens int `json:"cached_tokens,omitempty"`
+	OutputTokens int `json:"output_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
+
+func (u *Usage) Add(o Usage) {
+	u.InputTokens += o.InputTokens
+	u.CachedTokens += o.CachedTokens
+	u.OutputTokens += o.OutputTokens
+	u.TotalTokens += o.TotalTokens
 }
 
 type ResponseEnvelope struct {
 	ID         string         `json:"id"`
 	OutputText string         `json:"output_text"`
+	ToolCalls  []FunctionCall `json:"tool_calls,omitempty"`
+	Usage      Usage          `json:"usage"`
 	Raw        map[string]any `json:"-"`
 }
 
@@ -42,35 +120,99 @@ type embeddingsRequest struct {
 
 type embeddingsResponse struct {
 	Data []struct {
+		Index     int       `json:"index"`
 		Embedding []float64 `json:"embedding"`
 	} `json:"data"`
 }
 
+const DefaultBaseURL = "https://api.openai.com/v1"
+
 type Client struct {
 	apiKey     string
+	baseURL    string
+	headers    http.Header
 	httpClient *http.Client
 }
 
-func NewClient(apiKey string) *Client {
-	return &Client{
-		apiKey: apiKey,
+type ClientOption func(*Client)
+
+// WithBaseURL troca o endpoint (proxy corporativo, Azure, servidor compatível
+// ou httptest). Os caminhos /responses e /embeddings são anexados a ele.
+func WithBaseURL(u string) ClientOption {
+	return func(c *Client) {
+		if u != "" {
+			c.baseURL = strings.TrimRight(u, "/")
+		}
+	}
+}
+
+func WithOrganization(org string) ClientOption {
+	return WithHeader("OpenAI-Organization", org)
+}
+
+func WithProject(project string) ClientOption {
+	return WithHeader("OpenAI-Project", project)
+}
+
+// WithHeader adiciona um header a todas as requisições. Valores vazios são ignorados.
+func WithHeader(key, value string) ClientOption {
+	return func(c *Client) {
+		if key != "" && value != "" {
+			c.headers.Set(key, value)
+		}
+	}
+}
+
+func WithTransport(rt http.RoundTripper) ClientOption {
+	return func(c *Client) {
+		if rt != nil {
+			c.httpClient.Transport = rt
+		}
+	}
+}
+
+func NewClient(apiKey string, opts ...ClientOption) *Client {
+	c := &Client{
+		apiKey:  apiKey,
+		baseURL: DefaultBaseURL,
+		headers: http.Header{},
 		httpClient: &http.Client{
 			Timeout: 60 * time.Second,
 		},
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *Client) newRequest(ctx context.Context, path string, body []byte) (*http.Request, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if c.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	for k, v := range c.headers {
+		httpReq.Header[k] = v
+	}
+	return httpReq, nil
 }
 
 func (c *Client) Respond(ctx context.Context, req *ResponsesRequest) (*ResponseEnvelope, error) {
 	body, _ := json.Marshal(req)
 
-	httpReq, _ := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/responses", bytes.NewBuffer(body))
-	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
-	httpReq.Header.Set("Content-Type", "application/json")
-
 	// Retry simples para 5xx
 	var resp *http.Response
 	var err error
 	for i := 0; i < 3; i++ {
+		var httpReq *http.Request
+		httpReq, err = c.newRequest(ctx, "/responses", body)
+		if err != nil {
+			return nil, err
+		}
 		resp, err = c.httpClient.Do(httpReq)
 		if err != nil {
 			if i == 2 {
@@ -105,36 +247,83 @@ func (c *Client) Respond(ctx context.Context, req *ResponsesRequest) (*ResponseE
 		return nil, err
 	}
 
+	return parseEnvelope(raw), nil
+}
+
+func parseEnvelope(raw map[string]any) *ResponseEnvelope {
 	out := &ResponseEnvelope{Raw: raw}
 	if v, ok := raw["id"].(string); ok {
 		out.ID = v
 	}
 
-	if outputArr, ok := raw["output"].([]any); ok && len(outputArr) > 0 {
-		if first, ok := outputArr[0].(map[string]any); ok {
-			if content, ok := first["content"].([]any); ok && len(content) > 0 {
-				if c0, ok := content[0].(map[string]any); ok {
-					if txt, ok := c0["text"].(string); ok {
-						out.OutputText = txt
-					}
+	outputArr, _ := raw["output"].([]any)
+	var sb strings.Builder
+	for _, it := range outputArr {
+		item, ok := it.(map[string]any)
+		if !ok {
+			continue
+		}
+		switch item["type"] {
+		case "message":
+			content, _ := item["content"].([]any)
+			for _, c := range content {
+				ci, ok := c.(map[string]any)
+				if !ok || ci["type"] != "output_text" {
+					continue
+				}
+				if txt, ok := ci["text"].(string); ok {
+					sb.WriteString(txt)
 				}
 			}
+		case "function_call":
+			fc := FunctionCall{}
+			fc.ID, _ = item["id"].(string)
+			fc.CallID, _ = item["call_id"].(string)
+			fc.Name, _ = item["name"].(string)
+			fc.Arguments, _ = item["arguments"].(string)
+			out.ToolCalls = append(out.ToolCalls, fc)
 		}
 	}
+	out.OutputText = sb.String()
 
-	return out, nil
+	if u, ok := raw["usage"].(map[string]any); ok {
+		out.Usage.InputTokens = jsonInt(u["input_tokens"])
+		out.Usage.OutputTokens = jsonInt(u["output_tokens"])
+		out.Usage.TotalTokens = jsonInt(u["total_tokens"])
+		if d, ok := u["input_tokens_details"].(map[string]any); ok {
+			out.Usage.CachedTokens = jsonInt(d["cached_tokens"])
+		}
+	}
+
+	return out
+}
+
+func jsonInt(v any) int {
+	f, _ := v.(float64)
+	return int(f)
 }
 
 func (c *Client) Embed(ctx context.Context, model string, text string) ([]float32, error) {
+	out, err := c.EmbedBatch(ctx, model, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return out[0], nil
+}
+
+// EmbedBatch gera os embeddings de vários textos em uma requisição, na ordem
+// de texts.
+func (c *Client) EmbedBatch(ctx context.Context, model string, texts []string) ([][]float32, error) {
 	req := embeddingsRequest{
 		Model: model,
-		Input: []string{text},
+		Input: texts,
 	}
 	body, _ := json.Marshal(req)
 
-	httpReq, _ := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/embeddings", bytes.NewBuffer(body))
-	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
-	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq, err := c.newRequest(ctx, "/embeddings", body)
+	if err != nil {
+		return nil, err
+	}
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
@@ -152,14 +341,25 @@ func (c *Client) Embed(ctx context.Context, model string, text string) ([]float3
 	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
 		return nil, err
 	}
-	if len(out.Data) == 0 || len(out.Data[0].Embedding) == 0 {
+	if len(out.Data) != len(texts) {
 		return nil, fmt.Errorf("empty embedding response")
 	}
 
-	src := out.Data[0].Embedding
-	dst := make([]float32, len(src))
-	for i, v := range src {
-		dst[i] = float32(v)
+	vecs := make([][]float32, len(texts))
+	for _, d := range out.Data {
+		if d.Index < 0 || d.Index >= len(texts) || len(d.Embedding) == 0 {
+			return nil, fmt.Errorf("empty embedding response")
+		}
+		dst := make([]float32, len(d.Embedding))
+		for i, v := range d.Embedding {
+			dst[i] = float32(v)
+		}
+		vecs[d.Index] = dst
+	}
+	for _, v := range vecs {
+		if v == nil {
+			return nil, fmt.Errorf("empty embedding response")
+		}
 	}
-	return dst, nil
+	return vecs, nil
 }
@@ -0,0 +1,36 @@
+package openai
+
+import "context"
+
+// Embedder gera embeddings; é tudo que as tools de busca semântica precisam.
+type Embedder interface {
+	Embed(ctx context.Context, model string, text string) ([]float32, error)
+}
+
+// BatchEmbedder é implementado por embedders que aceitam vários textos por
+// requisição; a ingestão de documentos usa quando disponível.
+type BatchEmbedder interface {
+	Embedder
+	EmbedBatch(ctx context.Context, model string, texts []string) ([][]float32, error)
+}
+
+// Provider é o contrato de LLM usado pelo agent, pelo router e pelas tools.
+// *Client é a implementação para a API da OpenAI; qualquer servidor que fale o
+// formato da Responses API (Azure, vLLM, Ollama...) ou um fake de testes pode
+// ser usado no lugar.
+type Provider interface {
+	Embedder
+	Respond(ctx context.Context, req *ResponsesRequest) (*ResponseEnvelope, error)
+}
+
+// StreamProvider é implementado por providers que suportam streaming. Os que
+// não implementam têm a resposta entregue de uma vez em RunStream.
+type StreamProvider interface {
+	Provider
+	RespondStream(ctx context.Context, req *ResponsesRequest) (<-chan StreamEvent, error)
+}
+
+var (
+	_ StreamProvider = (*Client)(nil)
+	_ BatchEmbedder  = (*Client)(nil)
+)
@@ -0,0 +1,147 @@
+package openai
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// StreamEvent é um evento do stream SSE da Responses API. Delta traz pedaços de
+// texto, ToolCall uma function call completa e Response o envelope final. Err
+// encerra o stream.
+type StreamEvent struct {
+	Type     string
+	Delta    string
+	ToolCall *FunctionCall
+	Response *ResponseEnvelope
+	Err      error
+}
+
+// RespondStream envia o request com stream=true e entrega os eventos no canal,
+// que é fechado após o envelope final ou um erro.
+func (c *Client) RespondStream(ctx context.Context, req *ResponsesRequest) (<-chan StreamEvent, error) {
+	sreq := *req
+	sreq.Stream = true
+	body, _ := json.Marshal(&sreq)
+
+	httpReq, err := c.newRequest(ctx, "/responses", body)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Accept", "text/event-stream")
+
+	// o timeout do client cortaria streams longos; o limite fica por conta do ctx
+	hc := *c.httpClient
+	hc.Timeout = 0
+
+	resp, err := hc.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		var errBody map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&errBody)
+		return nil, fmt.Errorf("openai error: %s\n%v", resp.Status, errBody)
+	}
+
+	ch := make(chan StreamEvent, 16)
+	go func() {
+		defer close(ch)
+		defer resp.Body.Close()
+
+		send := func(ev StreamEvent) bool {
+			select {
+			case ch <- ev:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		sc := bufio.NewScanner(resp.Body)
+		sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+		var data strings.Builder
+		for sc.Scan() {
+			line := sc.Text()
+			if line != "" {
+				if strings.HasPrefix(line, "data:") {
+					if data.Len() > 0 {
+						data.WriteByte('\n')
+					}
+					data.WriteString(strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+				}
+				continue
+			}
+			if data.Len() == 0 {
+				continue
+			}
+			payload := data.String()
+			data.Reset()
+			if payload == "[DONE]" {
+				break
+			}
+
+			ev, final := decodeStreamEvent(payload)
+			if ev == nil {
+				continue
+			}
+			if !send(*ev) || final {
+				return
+			}
+		}
+		if err := sc.Err(); err != nil {
+			send(StreamEvent{Type: "error", Err: err})
+			return
+		}
+		send(StreamEvent{Type: "error", Err: errors.New("openai stream ended without response.completed")})
+	}()
+
+	return ch, nil
+}
+
+// decodeStreamEvent interpreta um payload SSE. final indica que o stream acabou.
+func decodeStreamEvent(payload string) (ev *StreamEvent, final bool) {
+	var raw map[string]any
+	if err := json.Unmarshal([]byte(payload), &raw); err != nil {
+		return &StreamEvent{Type: "error", Err: fmt.Errorf("openai stream: %w", err)}, true
+	}
+	typ, _ := raw["type"].(string)
+
+	switch typ {
+	case "response.output_text.delta":
+		delta, _ := raw["delta"].(string)
+		return &StreamEvent{Type: typ, Delta: delta}, false
+
+	case "response.output_item.done":
+		item, _ := raw["item"].(map[string]any)
+		if item == nil || item["type"] != "function_call" {
+			return nil, false
+		}
+		fc := &FunctionCall{}
+		fc.ID, _ = item["id"].(string)
+		fc.CallID, _ = item["call_id"].(string)
+		fc.Name, _ = item["name"].(string)
+		fc.Arguments, _ = item["arguments"].(string)
+		return &StreamEvent{Type: typ, ToolCall: fc}, false
+
+	case "response.completed", "response.incomplete":
+		r, _ := raw["response"].(map[string]any)
+		if r == nil {
+			return &StreamEvent{Type: "error", Err: fmt.Errorf("openai stream: %s sem response", typ)}, true
+		}
+		return &StreamEvent{Type: typ, Response: parseEnvelope(r)}, true
+
+	case "response.failed":
+		r, _ := raw["response"].(map[string]any)
+		return &StreamEvent{Type: typ, Err: fmt.Errorf("openai response failed: %v", r["error"])}, true
+
+	case "error":
+		return &StreamEvent{Type: typ, Err: fmt.Errorf("openai stream error: %v", raw["message"])}, true
+	}
+	return nil, false
+}
@@ -0,0 +1,106 @@
+package openai
+
+import "testing"
+
+func TestDecodeStreamEvent(t *testing.T) {
+	tests := []struct {
+		name      string
+		payload   string
+		wantNil   bool
+		wantType  string
+		wantFinal bool
+		wantErr   bool
+		check     func(t *testing.T, ev *StreamEvent)
+	}{
+		{
+			name:     "delta",
+			payload:  `{"type":"response.output_text.delta","delta":"Olá"}`,
+			wantType: "response.output_text.delta",
+			check: func(t *testing.T, ev *StreamEvent) {
+				if ev.Delta != "Olá" {
+					t.Errorf("delta = %q", ev.Delta)
+				}
+			},
+		},
+		{
+			name:     "function call",
+			payload:  `{"type":"response.output_item.done","item":{"type":"function_call","id":"fc_1","call_id":"call_1","name":"busca","arguments":"{\"q\":\"x\"}"}}`,
+			wantType: "response.output_item.done",
+			check: func(t *testing.T, ev *StreamEvent) {
+				fc := ev.ToolCall
+				if fc == nil || fc.CallID != "call_1" || fc.Name != "busca" || fc.Arguments != `{"q":"x"}` {
+					t.Errorf("tool call = %+v", fc)
+				}
+			},
+		},
+		{
+			name:    "item que não é function call",
+			payload: `{"type":"response.output_item.done","item":{"type":"message"}}`,
+			wantNil: true,
+		},
+		{
+			name:      "completed",
+			payload:   `{"type":"response.completed","response":{"id":"r1","output":[{"type":"message","content":[{"type":"output_text","text":"fim"}]}]}}`,
+			wantType:  "response.completed",
+			wantFinal: true,
+			check: func(t *testing.T, ev *StreamEvent) {
+				if ev.Response == nil || ev.Response.ID != "r1" || ev.Response.OutputText != "fim" {
+					t.Errorf("response = %+v", ev.Response)
+				}
+			},
+		},
+		{
+			name:      "completed sem response",
+			payload:   `{"type":"response.completed"}`,
+			wantType:  "error",
+			wantFinal: true,
+			wantErr:   true,
+		},
+		{
+			name:      "failed",
+			payload:   `{"type":"response.failed","response":{"error":{"message":"x"}}}`,
+			wantType:  "response.failed",
+			wantFinal: true,
+			wantErr:   true,
+		},
+		{
+			name:      "error",
+			payload:   `{"type":"error","message":"rate limit"}`,
+			wantType:  "error",
+			wantFinal: true,
+			wantErr:   true,
+		},
+		{
+			name:      "json inválido",
+			payload:   `{"type":`,
+			wantType:  "error",
+			wantFinal: true,
+			wantErr:   true,
+		},
+		{
+			name:    "evento ignorado",
+			payload: `{"type":"response.created","response":{}}`,
+			wantNil: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ev, final := decodeStreamEvent(tt.payload)
+			if tt.wantNil {
+				if ev != nil || final {
+					t.Fatalf("got %+v final=%v, quer nil", ev, final)
+				}
+				return
+			}
+			if ev == nil {
+				t.Fatal("evento nil")
+			}
+			if ev.Type != tt.wantType || final != tt.wantFinal || (ev.Err != nil) != tt.wantErr {
+				t.Fatalf("got type=%q final=%v err=%v", ev.Type, final, ev.Err)
+			}
+			if tt.check != nil {
+				tt.check(t, ev)
+			}
+		})
+	}
+}
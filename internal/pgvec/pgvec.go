@@ -0,0 +1,36 @@
+// Package pgvec formata vetores para o pgvector. Fica fora de tools e memory
+// para os dois (e a ingestão) usarem sem depender um do outro.
+package pgvec
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Encode formata o vetor no texto aceito pelo pgvector ([1,2.5,...]). Cada
+// componente sai na menor forma que o pgvector lê de volta como o mesmo float32;
+// com %f (seis casas) componentes pequenos perdiam precisão ou viravam 0.
+func Encode(v []float32) string {
+	if len(v) == 0 {
+		return "[]"
+	}
+	var sb strings.Builder
+	sb.WriteByte('[')
+	for i, x := range v {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(formatFloat(x))
+	}
+	sb.WriteByte(']')
+	return sb.String()
+}
+
+// formatFloat usa a menor forma que preserva o float32; NaN e Inf viram 0.
+func formatFloat(x float32) string {
+	s := strconv.FormatFloat(float64(x), 'f', -1, 32)
+	if s == "NaN" || s == "+Inf" || s == "-Inf" {
+		return "0"
+	}
+	return s
+}
@@ -0,0 +1,59 @@
+package pgvec
+
+import (
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		in   []float32
+		want string
+	}{
+		{nil, "[]"},
+		{[]float32{1, -2.5, 0}, "[1,-2.5,0]"},
+		{[]float32{0.0000123}, "[0.0000123]"},
+		{[]float32{float32(math.NaN()), float32(math.Inf(1))}, "[0,0]"},
+	}
+	for _, tt := range tests {
+		if got := Encode(tt.in); got != tt.want {
+			t.Errorf("Encode(%v) = %s, quer %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+// decode lê o texto como o pgvector: cada componente vira float32.
+func decode(t *testing.T, s string) []float32 {
+	t.Helper()
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	if s == "" {
+		return nil
+	}
+	var out []float32
+	for _, part := range strings.Split(s, ",") {
+		f, err := strconv.ParseFloat(part, 32)
+		if err != nil {
+			t.Fatalf("componente %q: %v", part, err)
+		}
+		out = append(out, float32(f))
+	}
+	return out
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	vectors := [][]float32{
+		{0.1, 0.2, 0.3},
+		{1e-7, -3.4e-9, 0.000123456},
+		{math.MaxFloat32, -math.SmallestNonzeroFloat32, 1},
+		{0.0123456789, -0.98765432, 0.5},
+	}
+	for _, v := range vectors {
+		got := decode(t, Encode(v))
+		if !slices.Equal(got, v) {
+			t.Errorf("Encode(%v) = %s, lido de volta como %v", v, Encode(v), got)
+		}
+	}
+}
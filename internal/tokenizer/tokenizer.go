@@ -0,0 +1,192 @@
+package tokenizer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Tokenizer conta tokens de um texto no vocabulário do modelo.
+type Tokenizer interface {
+	Count(text string) int
+}
+
+// pretokenRe segue o padrão de pré-tokenização do cl100k/o200k. O RE2 não tem
+// lookahead, então a alternativa `\s+(?!\S)` fica de fora; a diferença é de no
+// máximo um token por sequência de espaços.
+var pretokenRe = regexp.MustCompile(`(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+`)
+
+// New carrega um vocabulário BPE no formato .tiktoken (token em base64 e rank
+// por linha), como o cl100k_base e o o200k_base usados pelos modelos da OpenAI.
+// Sem arquivo, devolve um estimador que não precisa de vocabulário.
+func New(path string) (Tokenizer, error) {
+	if path == "" {
+		return Estimator{}, nil
+	}
+	return LoadBPE(path)
+}
+
+// BPE é um tokenizador byte-pair encoding compatível com os vocabulários tiktoken.
+type BPE struct {
+	ranks map[string]int
+
+	mu    sync.Mutex
+	cache map[string]int
+}
+
+const maxCache = 20000
+
+func LoadBPE(path string) (*BPE, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ranks := make(map[string]int, 200000)
+	sc := bufio.NewScanner(f)
+	line := 0
+	for sc.Scan() {
+		line++
+		tok, rank, ok := strings.Cut(strings.TrimSpace(sc.Text()), " ")
+		if !ok {
+			continue
+		}
+		b, err := base64.StdEncoding.DecodeString(tok)
+		if err != nil {
+			return nil, fmt.Errorf("tokenizer %s:%d: %w", path, line, err)
+		}
+		r, err := strconv.Atoi(rank)
+		if err != nil {
+			return nil, fmt.Errorf("tokenizer %s:%d: %w", path, line, err)
+		}
+		ranks[string(b)] = r
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(ranks) == 0 {
+		return nil, fmt.Errorf("tokenizer %s: vocabulário vazio", path)
+	}
+	return &BPE{ranks: ranks, cache: map[string]int{}}, nil
+}
+
+func (t *BPE) Count(text string) int {
+	n := 0
+	for _, piece := range pretokenRe.FindAllString(text, -1) {
+		n += t.countPiece(piece)
+	}
+	return n
+}
+
+func (t *BPE) countPiece(piece string) int {
+	if _, ok := t.ranks[piece]; ok {
+		return 1
+	}
+	t.mu.Lock()
+	n, ok := t.cache[piece]
+	t.mu.Unlock()
+	if ok {
+		return n
+	}
+
+	n = len(t.merge([]byte(piece)))
+
+	t.mu.Lock()
+	if len(t.cache) >= maxCache {
+		t.cache = map[string]int{}
+	}
+	t.cache[piece] = n
+	t.mu.Unlock()
+	return n
+}
+
+// merge aplica as fusões de menor rank até não haver par conhecido.
+func (t *BPE) merge(b []byte) []string {
+	parts := make([]string, len(b))
+	for i := range b {
+		parts[i] = string(b[i : i+1])
+	}
+	for len(parts) > 1 {
+		best, bestRank := -1, int(^uint(0)>>1)
+		for i := 0; i < len(parts)-1; i++ {
+			if r, ok := t.ranks[parts[i]+parts[i+1]]; ok && r < bestRank {
+				best, bestRank = i, r
+			}
+		}
+		if best < 0 {
+			break
+		}
+		parts[best] += parts[best+1]
+		parts = append(parts[:best+1], parts[best+2:]...)
+	}
+	return parts
+}
+
+// Estimator aproxima a contagem sem vocabulário, usando a mesma pré-tokenização
+// do BPE: ~4 bytes por token em texto ASCII e ~2 em texto acentuado/não latino.
+type Estimator struct{}
+
+func (Estimator) Count(text string) int {
+	n := 0
+	for _, piece := range pretokenRe.FindAllString(text, -1) {
+		size := len(piece)
+		if utf8.RuneCountInString(piece) == size {
+			n += (size + 3) / 4
+		} else {
+			n += (size + 1) / 2
+		}
+	}
+	return n
+}
+
+// Truncate corta text para caber em max tokens, preservando o início. Conta cada
+// pedaço da pré-tokenização uma vez e só procura o corte dentro do pedaço que
+// estoura o limite.
+func Truncate(tok Tokenizer, text string, max int) (string, bool) {
+	if max <= 0 {
+		return "", text != ""
+	}
+	used := 0
+	for _, loc := range pretokenRe.FindAllStringIndex(text, -1) {
+		n := tok.Count(text[loc[0]:loc[1]])
+		if used+n <= max {
+			used += n
+			continue
+		}
+		out := text[:loc[0]] + truncateSearch(tok, text[loc[0]:loc[1]], max-used)
+		// tokenizadores que não somam por pedaço caem na busca completa
+		if tok.Count(out) > max {
+			return truncateSearch(tok, text, max), true
+		}
+		return out, true
+	}
+	if tok.Count(text) <= max {
+		return text, false
+	}
+	return truncateSearch(tok, text, max), true
+}
+
+// truncateSearch acha por busca binária o maior prefixo que cabe em max tokens.
+func truncateSearch(tok Tokenizer, text string, max int) string {
+	if max <= 0 {
+		return ""
+	}
+	runes := []rune(text)
+	lo, hi := 0, len(runes)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if tok.Count(string(runes[:mid])) <= max {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return string(runes[:lo])
+}
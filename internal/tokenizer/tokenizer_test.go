@@ -0,0 +1,130 @@
+package tokenizer
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// testdata/mini.tiktoken tem os 256 bytes e as fusões he, ll, hell, hello,
+// " w", or, " wor", ld, " world", 12 e 123.
+func loadMini(t *testing.T) *BPE {
+	t.Helper()
+	bpe, err := LoadBPE("testdata/mini.tiktoken")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bpe
+}
+
+func TestBPECount(t *testing.T) {
+	bpe := loadMini(t)
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"hello", 1},
+		{"hello world", 2},
+		{"hellx", 2},   // hell + x
+		{"hel", 2},     // he + l
+		{" worlds", 2}, // " world" + s
+		{"hello, world!", 4},
+		{"1234", 2},           // 123 + 4
+		{"ção", 5},            // bytes sem fusão
+		{"hello\n\nhello", 4}, // "\n\n" são dois bytes
+	}
+	for _, tt := range tests {
+		if got := bpe.Count(tt.text); got != tt.want {
+			t.Errorf("Count(%q) = %d, quer %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestBPEMerge(t *testing.T) {
+	bpe := loadMini(t)
+	got := bpe.merge([]byte(" worldly"))
+	want := []string{" world", "l", "y"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("merge = %q, quer %q", got, want)
+	}
+}
+
+func TestLoadBPEErrors(t *testing.T) {
+	if _, err := LoadBPE("testdata/nao-existe.tiktoken"); err == nil {
+		t.Error("arquivo ausente aceito")
+	}
+	tok, err := New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := tok.(Estimator); !ok {
+		t.Errorf("New(\"\") = %T, quer Estimator", tok)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	texts := []string{
+		"hello world, hello worlds!",
+		strings.Repeat("hello world ", 40),
+		"ação rápida: 12345 itens\n\nfim",
+		"hellohellohellohellohello",
+	}
+	toks := map[string]Tokenizer{"estimator": Estimator{}, "bpe": loadMini(t)}
+	for name, tok := range toks {
+		for _, text := range texts {
+			total := tok.Count(text)
+			for max := 0; max <= total+1; max++ {
+				out, cut := Truncate(tok, text, max)
+				if !strings.HasPrefix(text, out) || !utf8.ValidString(out) {
+					t.Fatalf("%s: Truncate(%q, %d) = %q não é prefixo válido", name, text, max, out)
+				}
+				if n := tok.Count(out); n > max {
+					t.Errorf("%s: Truncate(%q, %d) = %q com %d tokens", name, text, max, out, n)
+				}
+				if cut != (total > max) {
+					t.Errorf("%s: Truncate(%q, %d) cut = %v", name, text, max, cut)
+				}
+			}
+		}
+	}
+}
+
+// O Estimator soma por pedaço, então o corte é o maior prefixo que cabe.
+func TestTruncateKeepsLongestPrefix(t *testing.T) {
+	tok := Estimator{}
+	text := "o orçamento do contexto corta o fim do prompt de sistema"
+	for max := 1; max < tok.Count(text); max++ {
+		out, _ := Truncate(tok, text, max)
+		_, size := utf8.DecodeRuneInString(text[len(out):])
+		if tok.Count(text[:len(out)+size]) <= max {
+			t.Errorf("Truncate(%d) = %q, mas cabia mais um caractere", max, out)
+		}
+	}
+}
+
+// countingTokenizer soma os bytes que passaram por Count.
+type countingTokenizer struct {
+	Tokenizer
+	bytes *int
+}
+
+func (c countingTokenizer) Count(text string) int {
+	*c.bytes += len(text)
+	return c.Tokenizer.Count(text)
+}
+
+// Truncate conta cada pedaço uma vez, em vez de recontar o prefixo inteiro a
+// cada passo de uma busca binária.
+func TestTruncateCost(t *testing.T) {
+	text := strings.Repeat("hello world ", 5000)
+	counted := 0
+	tok := countingTokenizer{Estimator{}, &counted}
+	if _, cut := Truncate(tok, text, tok.Count(text)/2); !cut {
+		t.Fatal("não cortou")
+	}
+	counted -= len(text) // a contagem usada para escolher o limite
+	if counted > 3*len(text) {
+		t.Errorf("Truncate contou %d bytes para um texto de %d", counted, len(text))
+	}
+}
@@ -0,0 +1,94 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type attributesKey struct{}
+
+// WithAttributes anexa ao contexto atributos da sessão (ex.: tenant_id) que os
+// filtros das tools podem usar como $session.nome. Eles vêm da aplicação, nunca
+// do modelo.
+func WithAttributes(ctx context.Context, attrs map[string]string) context.Context {
+	merged := map[string]string{}
+	for k, v := range Attributes(ctx) {
+		merged[k] = v
+	}
+	for k, v := range attrs {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, attributesKey{}, merged)
+}
+
+// Attributes devolve os atributos da sessão anexados ao contexto.
+func Attributes(ctx context.Context) map[string]string {
+	m, _ := ctx.Value(attributesKey{}).(map[string]string)
+	return m
+}
+
+var filterRefRe = regexp.MustCompile(`\$(session\.[A-Za-z_][A-Za-z0-9_]*|\d+)`)
+
+// bindFilter troca $N (argumento da tool) e $session.nome (atributo) no filtro
+// por parâmetros do statement via bind. Um filtro que só usa argumentos e os
+// recebeu todos vazios é ignorado (ok=false). Atributo ausente, ou filtro que
+// mistura atributo com argumentos vazios, é erro: a busca nunca roda sem a
+// restrição da sessão.
+func bindFilter(filter string, args []string, attrs map[string]string, bind func(any) string) (clause string, ok bool, err error) {
+	usesArg, hasArg, usesAttr := false, false, false
+	var values []any
+	for _, m := range filterRefRe.FindAllStringSubmatch(filter, -1) {
+		ref := m[1]
+		if name, isAttr := strings.CutPrefix(ref, "session."); isAttr {
+			v, found := attrs[name]
+			if !found {
+				return "", false, fmt.Errorf("atributo de sessão %s ausente para o filtro %q", name, filter)
+			}
+			usesAttr = true
+			values = append(values, v)
+			continue
+		}
+		usesArg = true
+		i, _ := strconv.Atoi(ref)
+		v := ""
+		if i >= 1 && i <= len(args) {
+			v = args[i-1]
+		}
+		if v != "" {
+			hasArg = true
+		}
+		values = append(values, v)
+	}
+	if usesArg && !hasArg {
+		if usesAttr {
+			return "", false, fmt.Errorf("o filtro %q usa atributo de sessão e argumentos vazios; separe a condição do atributo em outro filtro", filter)
+		}
+		return "", false, nil
+	}
+	n := 0
+	clause = filterRefRe.ReplaceAllStringFunc(filter, func(string) string {
+		v := values[n]
+		n++
+		return bind(v)
+	})
+	return clause, true, nil
+}
+
+// filterClauses monta as condições de filters para o WHERE, cada uma entre
+// parênteses.
+func filterClauses(filters, args []string, attrs map[string]string, bind func(any) string) ([]string, error) {
+	var out []string
+	for _, f := range filters {
+		clause, ok, err := bindFilter(f, args, attrs, bind)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out = append(out, "("+clause+")")
+		}
+	}
+	return out, nil
+}
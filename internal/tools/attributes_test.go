@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestFilterClauses(t *testing.T) {
+	attrs := map[string]string{"tenant": "t1"}
+	tests := []struct {
+		name     string
+		filters  []string
+		args     []string
+		attrs    map[string]string
+		want     []string
+		wantArgs []any
+		wantErr  bool
+	}{
+		{
+			name:     "argumento opcional vazio sai, sessão fica",
+			filters:  []string{"tenant_id = $session.tenant", "categoria = $2"},
+			args:     []string{"busca", ""},
+			attrs:    attrs,
+			want:     []string{"(tenant_id = $2)"},
+			wantArgs: []any{"t1"},
+		},
+		{
+			name:     "sessão e argumento juntos",
+			filters:  []string{"tenant_id = $session.tenant AND categoria = $2"},
+			args:     []string{"busca", "faq"},
+			attrs:    attrs,
+			want:     []string{"(tenant_id = $2 AND categoria = $3)"},
+			wantArgs: []any{"t1", "faq"},
+		},
+		{
+			name:    "sessão com argumento ausente é erro",
+			filters: []string{"tenant_id = $session.tenant AND categoria = $2"},
+			args:    []string{"busca"},
+			attrs:   attrs,
+			wantErr: true,
+		},
+		{
+			name:    "atributo ausente é erro",
+			filters: []string{"tenant_id = $session.tenant"},
+			args:    []string{"busca"},
+			wantErr: true,
+		},
+		{
+			name:     "um dos argumentos preenchido mantém o filtro",
+			filters:  []string{"(preco >= $2 OR $3 = '')"},
+			args:     []string{"busca", "10", ""},
+			want:     []string{"((preco >= $2 OR $3 = ''))"},
+			wantArgs: []any{"10", ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var qargs []any
+			bind := func(v any) string {
+				qargs = append(qargs, v)
+				return "$" + strconv.Itoa(len(qargs)+1)
+			}
+			got, err := filterClauses(tt.filters, tt.args, tt.attrs, bind)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, quer erro %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) || !reflect.DeepEqual(qargs, tt.wantArgs) {
+				t.Errorf("got %q %v, quer %q %v", got, qargs, tt.want, tt.wantArgs)
+			}
+			if tt.attrs != nil && strings.Contains(strings.Join(tt.filters, " "), "$session.") &&
+				!strings.Contains(strings.Join(got, " "), "tenant_id") {
+				t.Errorf("o filtro da sessão sumiu: %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formatos da saída das tools postgres.
+const (
+	FormatKV       = "kv"
+	FormatMarkdown = "markdown"
+	FormatJSONL    = "jsonl"
+	FormatCSV      = "csv"
+)
+
+// maxBytea é o tamanho a partir do qual um bytea sai só com o começo e o total.
+const maxBytea = 32
+
+// rowFormatter escreve o cabeçalho e as linhas de um resultado, cada chamada
+// devolvendo texto já terminado em "\n".
+type rowFormatter interface {
+	header() string
+	row(vals []any) string
+}
+
+func newRowFormatter(format string, cols []string) (rowFormatter, error) {
+	switch format {
+	case "", FormatKV:
+		return kvFormatter{cols}, nil
+	case FormatMarkdown:
+		return markdownFormatter{cols}, nil
+	case FormatJSONL:
+		return jsonlFormatter{cols}, nil
+	case FormatCSV:
+		return csvFormatter{cols}, nil
+	}
+	return nil, fmt.Errorf("format %q desconhecido (use kv, markdown, jsonl ou csv)", format)
+}
+
+// cellValue normaliza o valor lido pelo driver conforme o tipo da coluna: bytea
+// em hex, numeric como número, json como JSON, datas em ISO 8601. NULL vira nil.
+func cellValue(v any, dbType string) any {
+	switch x := v.(type) {
+	case nil:
+		return nil
+	case []byte:
+		switch dbType {
+		case "BYTEA":
+			if len(x) > maxBytea {
+				return fmt.Sprintf("\\x%s… (%d bytes)", hex.EncodeToString(x[:maxBytea]), len(x))
+			}
+			return "\\x" + hex.EncodeToString(x)
+		case "JSON", "JSONB":
+			var buf bytes.Buffer
+			if err := json.Compact(&buf, x); err == nil {
+				return json.RawMessage(buf.Bytes())
+			}
+		case "NUMERIC":
+			return json.Number(x)
+		}
+		return string(x)
+	case time.Time:
+		switch dbType {
+		case "DATE":
+			return x.Format("2006-01-02")
+		case "TIME", "TIMETZ":
+			return x.Format("15:04:05")
+		case "TIMESTAMP":
+			return x.Format("2006-01-02T15:04:05")
+		}
+		return x.Format(time.RFC3339)
+	}
+	return v
+}
+
+// cellText é o valor como texto para kv, markdown e csv.
+func cellText(v any) (string, bool) {
+	switch x := v.(type) {
+	case nil:
+		return "", false
+	case string:
+		return x, true
+	case json.RawMessage:
+		return string(x), true
+	case json.Number:
+		return string(x), true
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64), true
+	case float32:
+		return strconv.FormatFloat(float64(x), 'f', -1, 32), true
+	}
+	return fmt.Sprint(v), true
+}
+
+type kvFormatter struct{ cols []string }
+
+func (kvFormatter) header() string { return "" }
+
+func (f kvFormatter) row(vals []any) string {
+	var sb strings.Builder
+	for i, c := range f.cols {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		s, ok := cellText(vals[i])
+		if !ok {
+			s = "NULL"
+		}
+		sb.WriteString(c + "=" + kvEscaper.Replace(s))
+	}
+	sb.WriteByte('\n')
+	return sb.String()
+}
+
+// kvEscaper mantém cada registro em uma linha.
+var kvEscaper = strings.NewReplacer("\r\n", `\n`, "\n", `\n`)
+
+type markdownFormatter struct{ cols []string }
+
+func (f markdownFormatter) header() string {
+	var sb strings.Builder
+	sb.WriteString("|")
+	for _, c := range f.cols {
+		sb.WriteString(" " + markdownCell(c) + " |")
+	}
+	sb.WriteString("\n|")
+	for range f.cols {
+		sb.WriteString(" --- |")
+	}
+	sb.WriteByte('\n')
+	return sb.String()
+}
+
+func (f markdownFormatter) row(vals []any) string {
+	var sb strings.Builder
+	sb.WriteString("|")
+	for i := range f.cols {
+		s, ok := cellText(vals[i])
+		if !ok {
+			s = "NULL"
+		}
+		sb.WriteString(" " + markdownCell(s) + " |")
+	}
+	sb.WriteByte('\n')
+	return sb.String()
+}
+
+var markdownEscaper = strings.NewReplacer("|", `\|`, "\r\n", "<br>", "\n", "<br>")
+
+func markdownCell(s string) string { return markdownEscaper.Replace(s) }
+
+type jsonlFormatter struct{ cols []string }
+
+func (jsonlFormatter) header() string { return "" }
+
+// row monta o objeto à mão para manter a ordem das colunas.
+func (f jsonlFormatter) row(vals []any) string {
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i, c := range f.cols {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		k, _ := json.Marshal(c)
+		v, err := json.Marshal(vals[i])
+		if err != nil {
+			v, _ = json.Marshal(fmt.Sprint(vals[i]))
+		}
+		sb.Write(k)
+		sb.WriteByte(':')
+		sb.Write(v)
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+type csvFormatter struct{ cols []string }
+
+func (f csvFormatter) header() string { return csvLine(f.cols) }
+
+// row deixa NULL como campo vazio.
+func (f csvFormatter) row(vals []any) string {
+	rec := make([]string, len(f.cols))
+	for i := range f.cols {
+		rec[i], _ = cellText(vals[i])
+	}
+	return csvLine(rec)
+}
+
+func csvLine(rec []string) string {
+	var sb strings.Builder
+	w := csv.NewWriter(&sb)
+	w.Write(rec)
+	w.Flush()
+	return sb.String()
+}
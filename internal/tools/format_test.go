@@ -0,0 +1,118 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCellValue(t *testing.T) {
+	ts := time.Date(2024, 3, 5, 14, 7, 9, 0, time.FixedZone("BRT", -3*3600))
+	tests := []struct {
+		name   string
+		v      any
+		dbType string
+		want   string // valor em JSON
+	}{
+		{"null", nil, "TEXT", `null`},
+		{"bytea curto", []byte{0xde, 0xad}, "BYTEA", `"\\xdead"`},
+		{"bytea longo", bytes.Repeat([]byte{0xab}, 40), "BYTEA", `"\\x` + strings.Repeat("ab", 32) + `… (40 bytes)"`},
+		{"numeric", []byte("12.50"), "NUMERIC", `12.50`},
+		{"numeric NaN", []byte("NaN"), "NUMERIC", `"NaN"`},
+		{"json compactado", []byte("{ \"a\": [1, 2] }"), "JSONB", `{"a":[1,2]}`},
+		{"json inválido vira texto", []byte("{x"), "JSON", `"{x"`},
+		{"texto em bytes", []byte("olá"), "VARCHAR", `"olá"`},
+		{"timestamptz", ts, "TIMESTAMPTZ", `"2024-03-05T14:07:09-03:00"`},
+		{"timestamp", ts, "TIMESTAMP", `"2024-03-05T14:07:09"`},
+		{"date", ts, "DATE", `"2024-03-05"`},
+		{"time", ts, "TIME", `"14:07:09"`},
+		{"int", int64(7), "INT8", `7`},
+		{"bool", true, "BOOL", `true`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := jsonlFormatter{[]string{"c"}}.row([]any{cellValue(tt.v, tt.dbType)})
+			if want := `{"c":` + tt.want + "}\n"; got != want {
+				t.Errorf("got %s, quer %s", got, want)
+			}
+		})
+	}
+}
+
+func TestCellText(t *testing.T) {
+	tests := []struct {
+		v      any
+		want   string
+		wantOK bool
+	}{
+		{nil, "", false},
+		{"a", "a", true},
+		{json.RawMessage(`{"a":1}`), `{"a":1}`, true},
+		{json.Number("1.50"), "1.50", true},
+		{0.1, "0.1", true},
+		{float32(0.1), "0.1", true},
+		{int64(-3), "-3", true},
+		{false, "false", true},
+	}
+	for _, tt := range tests {
+		got, ok := cellText(tt.v)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("cellText(%#v) = %q, %v; quer %q, %v", tt.v, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestRowFormatters(t *testing.T) {
+	cols := []string{"id", "nota", "extra"}
+	vals := []any{int64(1), "linha 1\nlinha|2, \"citada\"", nil}
+	tests := []struct {
+		format     string
+		wantHeader string
+		wantRow    string
+	}{
+		{
+			format:  FormatKV,
+			wantRow: "id=1 nota=linha 1\\nlinha|2, \"citada\" extra=NULL\n",
+		},
+		{
+			format:     FormatMarkdown,
+			wantHeader: "| id | nota | extra |\n| --- | --- | --- |\n",
+			wantRow:    "| 1 | linha 1<br>linha\\|2, \"citada\" | NULL |\n",
+		},
+		{
+			format:  FormatJSONL,
+			wantRow: `{"id":1,"nota":"linha 1\nlinha|2, \"citada\"","extra":null}` + "\n",
+		},
+		{
+			format:     FormatCSV,
+			wantHeader: "id,nota,extra\n",
+			wantRow:    "1,\"linha 1\nlinha|2, \"\"citada\"\"\",\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			f, err := newRowFormatter(tt.format, cols)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.header(); got != tt.wantHeader {
+				t.Errorf("header = %q, quer %q", got, tt.wantHeader)
+			}
+			if got := f.row(vals); got != tt.wantRow {
+				t.Errorf("row = %q, quer %q", got, tt.wantRow)
+			}
+		})
+	}
+	if _, err := newRowFormatter("xml", cols); err == nil {
+		t.Error("format desconhecido aceito")
+	}
+}
+
+func TestMarkdownHeaderEscape(t *testing.T) {
+	got := markdownFormatter{[]string{"a|b"}}.header()
+	if want := "| a\\|b |\n| --- |\n"; got != want {
+		t.Errorf("got %q, quer %q", got, want)
+	}
+}
@@ -0,0 +1,260 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultHTTPTimeout = 15 * time.Second
+	// maxHTTPBody limita quanto da resposta é lido.
+	maxHTTPBody = 1 << 20
+)
+
+var (
+	envRefRe   = regexp.MustCompile(`ENV:([A-Za-z_][A-Za-z0-9_]*)`)
+	httpClient = &http.Client{}
+)
+
+// resolveEnvRefs troca cada ENV:NOME pelo valor da variável, também no meio do
+// texto ("Bearer ENV:API_TOKEN").
+func resolveEnvRefs(s string) string {
+	return envRefRe.ReplaceAllStringFunc(s, func(m string) string {
+		return os.Getenv(strings.TrimPrefix(m, "ENV:"))
+	})
+}
+
+// fillURL substitui $N na URL, escapando como segmento de path antes do "?" e
+// como valor de query depois. PathEscape não escapa ".", então um argumento
+// "." ou ".." no path é recusado para não subir de diretório na API.
+func fillURL(tpl string, args []string) (string, error) {
+	q := strings.IndexByte(tpl, '?')
+	var sb strings.Builder
+	last := 0
+	for _, m := range placeholderRe.FindAllStringSubmatchIndex(tpl, -1) {
+		i, _ := strconv.Atoi(tpl[m[2]:m[3]])
+		if i < 1 || i > len(args) {
+			continue
+		}
+		sb.WriteString(tpl[last:m[0]])
+		arg := args[i-1]
+		switch {
+		case q >= 0 && m[0] > q:
+			sb.WriteString(url.QueryEscape(arg))
+		case arg == "." || arg == "..":
+			return "", fmt.Errorf("argumento $%d inválido no path: %q", i, arg)
+		default:
+			sb.WriteString(url.PathEscape(arg))
+		}
+		last = m[1]
+	}
+	sb.WriteString(tpl[last:])
+	return sb.String(), nil
+}
+
+// fillBody percorre o body do tools.yml trocando $N pelos argumentos. Uma string
+// que é só "$N" vira o argumento inteiro, no tipo JSON do param; dentro de
+// texto, é substituída.
+func fillBody(v any, args []string, params []ParamConfig) any {
+	switch x := v.(type) {
+	case string:
+		if m := placeholderRe.FindStringSubmatch(x); m != nil && m[0] == x {
+			if i, _ := strconv.Atoi(m[1]); i >= 1 && i <= len(args) {
+				return typedArg(args[i-1], i-1, params)
+			}
+		}
+		return fillString(x, args)
+	case map[string]any:
+		out := make(map[string]any, len(x))
+		for k, it := range x {
+			out[k] = fillBody(it, args, params)
+		}
+		return out
+	case []any:
+		out := make([]any, len(x))
+		for i, it := range x {
+			out[i] = fillBody(it, args, params)
+		}
+		return out
+	}
+	return v
+}
+
+// typedArg converte o argumento já validado para o tipo JSON do param: número
+// para int/number, booleano para bool e null quando o opcional veio vazio.
+func typedArg(arg string, i int, params []ParamConfig) any {
+	if i >= len(params) {
+		return arg
+	}
+	var (
+		v   any
+		err error
+	)
+	switch params[i].Type {
+	case ParamInt:
+		v, err = strconv.ParseInt(arg, 10, 64)
+	case ParamNumber:
+		v, err = strconv.ParseFloat(arg, 64)
+	case ParamBool:
+		v, err = strconv.ParseBool(arg)
+	default:
+		return arg
+	}
+	if arg == "" {
+		return nil
+	}
+	if err != nil {
+		return arg
+	}
+	return v
+}
+
+func fillString(s string, args []string) string {
+	return placeholderRe.ReplaceAllStringFunc(s, func(m string) string {
+		i, _ := strconv.Atoi(m[1:])
+		if i < 1 || i > len(args) {
+			return m
+		}
+		return args[i-1]
+	})
+}
+
+// bodyPlaceholders devolve o maior $N usado no body.
+func bodyPlaceholders(v any) int {
+	n := 0
+	switch x := v.(type) {
+	case string:
+		n = countPlaceholders(x)
+	case map[string]any:
+		for _, it := range x {
+			n = max(n, bodyPlaceholders(it))
+		}
+	case []any:
+		for _, it := range x {
+			n = max(n, bodyPlaceholders(it))
+		}
+	}
+	return n
+}
+
+// ExecHTTP chama o endpoint da tool e devolve a parte da resposta selecionada
+// por extract/fields, em JSON compacto (um objeto por linha para listas).
+func ExecHTTP(ctx context.Context, cfg ToolConfig, args ...string) (string, error) {
+	if cfg.URL == "" {
+		return "", fmt.Errorf("tool %s mal configurada: url obrigatória", cfg.Name)
+	}
+	method := strings.ToUpper(cfg.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultHTTPTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var body io.Reader
+	if cfg.Body != nil {
+		js, err := json.Marshal(fillBody(cfg.Body, args, cfg.Params))
+		if err != nil {
+			return "", fmt.Errorf("body da tool %s: %w", cfg.Name, err)
+		}
+		body = bytes.NewReader(js)
+	}
+
+	target, err := fillURL(cfg.URL, args)
+	if err != nil {
+		return "", fmt.Errorf("tool %s: %w", cfg.Name, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range cfg.Headers {
+		req.Header.Set(k, fillString(v, args))
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPBody))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		snippet := strings.TrimSpace(string(raw))
+		if len(snippet) > 300 {
+			snippet = snippet[:300]
+		}
+		return "", fmt.Errorf("HTTP %d: %s", resp.StatusCode, snippet)
+	}
+	return formatHTTPResponse(cfg, raw)
+}
+
+func formatHTTPResponse(cfg ToolConfig, raw []byte) (string, error) {
+	var doc any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		// resposta que não é JSON vai como texto
+		if cfg.Extract != "" {
+			return "", fmt.Errorf("resposta da tool %s não é JSON para extract", cfg.Name)
+		}
+		text := strings.TrimSpace(string(raw))
+		if text == "" {
+			return "Nenhum resultado encontrado.", nil
+		}
+		return text, nil
+	}
+
+	if cfg.Extract != "" {
+		sel, err := selectPath(doc, cfg.Extract)
+		if err != nil {
+			return "", err
+		}
+		doc = sel
+	}
+	doc = pickFields(doc, cfg.Fields)
+
+	switch x := doc.(type) {
+	case nil:
+		return "Nenhum resultado encontrado.", nil
+	case string:
+		return x, nil
+	case []any:
+		if len(x) == 0 {
+			return "Nenhum resultado encontrado.", nil
+		}
+		var sb strings.Builder
+		for _, it := range x {
+			if s, ok := it.(string); ok {
+				sb.WriteString(s)
+			} else {
+				js, _ := json.Marshal(it)
+				sb.Write(js)
+			}
+			sb.WriteByte('\n')
+		}
+		return sb.String(), nil
+	}
+	js, err := json.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+	return string(js), nil
+}
@@ -0,0 +1,103 @@
+package tools
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFillURL(t *testing.T) {
+	tests := []struct {
+		tpl     string
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{"https://api/x/$1/orders", []string{"a b/c"}, "https://api/x/a%20b%2Fc/orders", false},
+		{"https://api/x?q=$1&s=$2", []string{"a b&c", "ok"}, "https://api/x?q=a+b%26c&s=ok", false},
+		{"https://api/$1?id=$1", []string{"a/b"}, "https://api/a%2Fb?id=a%2Fb", false},
+		{"https://api/$1/$10", []string{"a", "", "", "", "", "", "", "", "", "dez"}, "https://api/a/dez", false},
+		{"https://api/$2", []string{"a"}, "https://api/$2", false},
+		{"https://api/x/$1/orders", []string{".."}, "", true},
+		{"https://api/x/$1", []string{"."}, "", true},
+		{"https://api/x/$1.json", []string{"a..b"}, "https://api/x/a..b.json", false},
+		{"https://api/x?q=$1", []string{".."}, "https://api/x?q=..", false},
+	}
+	for _, tt := range tests {
+		got, err := fillURL(tt.tpl, tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("fillURL(%q, %q) err = %v, quer erro %v", tt.tpl, tt.args, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("fillURL(%q) = %q, quer %q", tt.tpl, got, tt.want)
+		}
+	}
+}
+
+func TestFillBody(t *testing.T) {
+	params := []ParamConfig{
+		{Name: "cliente"},
+		{Name: "limite", Type: ParamInt},
+		{Name: "valor", Type: ParamNumber},
+		{Name: "ativo", Type: ParamBool},
+	}
+	tests := []struct {
+		name   string
+		body   any
+		args   []string
+		params []ParamConfig
+		want   string
+	}{
+		{
+			name:   "string exata mantém texto",
+			body:   map[string]any{"c": "$1"},
+			args:   []string{"123", "", "", ""},
+			params: params,
+			want:   `{"c":"123"}`,
+		},
+		{
+			name:   "tipos do param viram JSON",
+			body:   map[string]any{"l": "$2", "v": "$3", "a": "$4"},
+			args:   []string{"x", "10", "2.5", "true"},
+			params: params,
+			want:   `{"a":true,"l":10,"v":2.5}`,
+		},
+		{
+			name:   "opcional tipado vazio vira null",
+			body:   map[string]any{"l": "$2"},
+			args:   []string{"x", "", "", ""},
+			params: params,
+			want:   `{"l":null}`,
+		},
+		{
+			name:   "dentro de texto continua string",
+			body:   map[string]any{"q": "limite $2 de $1"},
+			args:   []string{"ana", "5", "", ""},
+			params: params,
+			want:   `{"q":"limite 5 de ana"}`,
+		},
+		{
+			name: "sem params tudo é string",
+			body: []any{"$1", map[string]any{"n": "$2"}, 3.0},
+			args: []string{"a", "7"},
+			want: `["a",{"n":"7"},3]`,
+		},
+		{
+			name: "placeholder sem argumento fica",
+			body: map[string]any{"x": "$3"},
+			args: []string{"a"},
+			want: `{"x":"$3"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			js, err := json.Marshal(fillBody(tt.body, tt.args, tt.params))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(js) != tt.want {
+				t.Errorf("got %s, quer %s", js, tt.want)
+			}
+		})
+	}
+}
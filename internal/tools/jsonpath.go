@@ -0,0 +1,150 @@
+package tools
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// pathStep é um passo de um caminho de extração: chave de objeto, índice de
+// array ou [*] (todos os itens).
+type pathStep struct {
+	key   string
+	index int
+	all   bool
+}
+
+// parsePath aceita um subconjunto de JSONPath: "$.data.items[*].id",
+// "items[0].name", "result". O "$" inicial é opcional.
+func parsePath(path string) ([]pathStep, error) {
+	p := strings.TrimSpace(path)
+	p = strings.TrimPrefix(p, "$")
+	var steps []pathStep
+	for p != "" {
+		switch p[0] {
+		case '.':
+			p = p[1:]
+		case '[':
+			end := strings.IndexByte(p, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("caminho inválido %q: ] ausente", path)
+			}
+			inner := strings.Trim(strings.TrimSpace(p[1:end]), `'"`)
+			p = p[end+1:]
+			if inner == "" {
+				return nil, fmt.Errorf("caminho inválido %q: chave vazia", path)
+			}
+			if inner == "*" {
+				steps = append(steps, pathStep{all: true})
+				continue
+			}
+			if n, err := strconv.Atoi(inner); err == nil {
+				steps = append(steps, pathStep{index: n})
+				continue
+			}
+			steps = append(steps, pathStep{key: inner, index: -1})
+		default:
+			end := strings.IndexAny(p, ".[")
+			if end < 0 {
+				end = len(p)
+			}
+			key := p[:end]
+			p = p[end:]
+			if key == "*" {
+				steps = append(steps, pathStep{all: true})
+				continue
+			}
+			steps = append(steps, pathStep{key: key, index: -1})
+		}
+	}
+	return steps, nil
+}
+
+// selectPath aplica o caminho ao documento. Com [*] no caminho o resultado é a
+// lista dos valores encontrados; sem, é o valor único (nil se não existir).
+func selectPath(doc any, path string) (any, error) {
+	steps, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	nodes := []any{doc}
+	multi := false
+	for _, st := range steps {
+		var next []any
+		for _, n := range nodes {
+			switch {
+			case st.all:
+				switch x := n.(type) {
+				case []any:
+					next = append(next, x...)
+				case map[string]any:
+					for _, k := range sortedKeys(x) {
+						next = append(next, x[k])
+					}
+				}
+			case st.key != "":
+				if m, ok := n.(map[string]any); ok {
+					if v, ok := m[st.key]; ok {
+						next = append(next, v)
+					}
+				}
+			default:
+				if arr, ok := n.([]any); ok {
+					i := st.index
+					if i < 0 {
+						i += len(arr)
+					}
+					if i >= 0 && i < len(arr) {
+						next = append(next, arr[i])
+					}
+				}
+			}
+		}
+		if st.all {
+			multi = true
+		}
+		nodes = next
+	}
+	if multi {
+		return nodes, nil
+	}
+	if len(nodes) == 0 {
+		return nil, nil
+	}
+	return nodes[0], nil
+}
+
+// pickFields reduz cada objeto aos campos pedidos; cada campo pode ser um
+// caminho ("cliente.nome") e vira a chave no resultado.
+func pickFields(v any, fields []string) any {
+	if len(fields) == 0 {
+		return v
+	}
+	switch x := v.(type) {
+	case []any:
+		out := make([]any, len(x))
+		for i, it := range x {
+			out[i] = pickFields(it, fields)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(fields))
+		for _, f := range fields {
+			if sel, err := selectPath(x, f); err == nil && sel != nil {
+				out[f] = sel
+			}
+		}
+		return out
+	}
+	return v
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
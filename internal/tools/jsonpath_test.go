@@ -0,0 +1,83 @@
+package tools
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    []pathStep
+		wantErr bool
+	}{
+		{"$", nil, false},
+		{"result", []pathStep{{key: "result", index: -1}}, false},
+		{"$.data.items[*].id", []pathStep{
+			{key: "data", index: -1}, {key: "items", index: -1}, {all: true}, {key: "id", index: -1},
+		}, false},
+		{"items[0].name", []pathStep{{key: "items", index: -1}, {index: 0}, {key: "name", index: -1}}, false},
+		{"$['a b'][-1]", []pathStep{{key: "a b", index: -1}, {index: -1}}, false},
+		{"$.*", []pathStep{{all: true}}, false},
+		{"$.items[0", nil, true},
+		{"$['']", nil, true},
+		{"$.items[]", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parsePath(tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parsePath(%q) err = %v, quer erro %v", tt.path, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parsePath(%q) = %+v, quer %+v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestSelectPath(t *testing.T) {
+	var doc any
+	if err := json.Unmarshal([]byte(`{
+		"data": {"orders": [
+			{"id": 1, "status": "pago", "customer": {"name": "Ana"}},
+			{"id": 2, "status": "aberto", "customer": {"name": "Bia"}}
+		]},
+		"meta": {"b": 2, "a": 1}
+	}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"$.data.orders[0].id", `1`},
+		{"$.data.orders[-1].customer.name", `"Bia"`},
+		{"$.data.orders[*].status", `["pago","aberto"]`},
+		{"$.data.orders[*].customer['name']", `["Ana","Bia"]`},
+		{"$.meta.*", `[1,2]`},
+		{"$.data.orders[5]", `null`},
+		{"$.nada", `null`},
+		{"$.nada[*]", `null`},
+	}
+	for _, tt := range tests {
+		got, err := selectPath(doc, tt.path)
+		if err != nil {
+			t.Errorf("selectPath(%q): %v", tt.path, err)
+			continue
+		}
+		js, _ := json.Marshal(got)
+		if string(js) != tt.want {
+			t.Errorf("selectPath(%q) = %s, quer %s", tt.path, js, tt.want)
+		}
+	}
+}
+
+func TestPickFields(t *testing.T) {
+	var doc any
+	_ = json.Unmarshal([]byte(`[{"id":1,"x":true,"c":{"n":"Ana"}},{"id":2}]`), &doc)
+	js, _ := json.Marshal(pickFields(doc, []string{"id", "c.n"}))
+	if want := `[{"c.n":"Ana","id":1},{"id":2}]`; string(js) != want {
+		t.Errorf("got %s, quer %s", js, want)
+	}
+}
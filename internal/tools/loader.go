@@ -1,8 +1,10 @@
 package tools
 
 import (
+	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -14,31 +16,91 @@ type ToolConfig struct {
 	Conn          string `yaml:"conn,omitempty"`
 	QueryTemplate string `yaml:"query_template,omitempty"`
 
+	// Limites do pool do DSN; sobrepõem o "pool" do topo do tools.yml
+	Pool PoolConfig `yaml:"pool,omitempty"`
+
+	// Execução das tools postgres: transação somente leitura (padrão true),
+	// statement_timeout, linhas e bytes máximos da saída. Zero usa o padrão e
+	// negativo desliga o limite.
+	ReadOnly         *bool         `yaml:"read_only,omitempty"`
+	StatementTimeout time.Duration `yaml:"statement_timeout,omitempty"`
+	MaxRows          int           `yaml:"max_rows,omitempty"`
+	MaxOutputBytes   int           `yaml:"max_output_bytes,omitempty"`
+
+	// Formato da saída das tools postgres: kv (padrão), markdown, jsonl ou csv
+	Format string `yaml:"format,omitempty"`
+
 	// Para embeddings
 	Table          string `yaml:"table,omitempty"`
 	Column         string `yaml:"column,omitempty"`
 	EmbeddingModel string `yaml:"embedding_model,omitempty"`
 	TopK           int    `yaml:"top_k,omitempty"`
 
+	// Busca semântica: coluna do vetor (padrão embedding), distance (cosine,
+	// l2 ou inner_product), distância máxima, filtros SQL com $N (argumento)
+	// ou $session.nome (atributo da sessão) e colunas devolvidas com o score.
+	EmbeddingColumn string   `yaml:"embedding_column,omitempty"`
+	Distance        string   `yaml:"distance,omitempty"`
+	MaxDistance     *float64 `yaml:"max_distance,omitempty"`
+	Filters         []string `yaml:"filters,omitempty"`
+	Columns         []string `yaml:"columns,omitempty"`
+
 	// Para scripts
 	Path     string `yaml:"path,omitempty"`
 	Function string `yaml:"function,omitempty"`
+
+	// Para http: $N na url, nos headers e nas strings do body vira o argumento.
+	// extract seleciona parte da resposta JSON ("$.data.items[*]") e fields
+	// reduz cada objeto aos campos listados.
+	Method  string            `yaml:"method,omitempty"`
+	URL     string            `yaml:"url,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	Body    any               `yaml:"body,omitempty"`
+	Timeout time.Duration     `yaml:"timeout,omitempty"`
+	Extract string            `yaml:"extract,omitempty"`
+	Fields  []string          `yaml:"fields,omitempty"`
+
+	// Parâmetros nomeados e tipados, na ordem de $1, $2...
+	Params []ParamConfig `yaml:"params,omitempty"`
+
+	// Campos da saída que viram fatos da sessão
+	Facts []FactConfig `yaml:"facts,omitempty"`
+}
+
+// FactConfig declara como a saída da tool vira fatos da sessão. A chave do fato
+// vem de um argumento (key_arg: arg1, $1, query) ou de uma coluna da saída
+// (key_column); cada coluna em fields vira um fato.
+type FactConfig struct {
+	Category  string   `yaml:"category"`
+	KeyArg    string   `yaml:"key_arg,omitempty"`
+	KeyColumn string   `yaml:"key_column,omitempty"`
+	Fields    []string `yaml:"fields"`
 }
 
 type Config struct {
+	// Limites padrão dos pools das tools postgres
+	Pool  PoolConfig   `yaml:"pool"`
 	Tools []ToolConfig `yaml:"tools"`
 }
 
-var loaded Config
+// Catalog é o conjunto de tools carregado de um tools.yml, junto do registry de
+// scripts que elas podem chamar e dos pools de conexão. Cada agent tem o seu.
+type Catalog struct {
+	tools   []ToolConfig
+	Scripts *Registry
+	Pools   *Pools
+}
 
-func LoadTools(path string) error {
+// LoadTools lê o tools.yml. Se scripts for nil, um registry próprio é criado
+// com DefaultRegistry como parent.
+func LoadTools(path string, scripts *Registry) (*Catalog, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	var cfg Config
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return err
+		return nil, err
 	}
 
 	for i := range cfg.Tools {
@@ -46,17 +108,60 @@ func LoadTools(path string) error {
 			envKey := strings.TrimPrefix(cfg.Tools[i].Conn, "ENV:")
 			cfg.Tools[i].Conn = os.Getenv(envKey)
 		}
+		cfg.Tools[i].URL = resolveEnvRefs(cfg.Tools[i].URL)
+		for k, v := range cfg.Tools[i].Headers {
+			cfg.Tools[i].Headers[k] = resolveEnvRefs(v)
+		}
+		if err := cfg.Tools[i].check(); err != nil {
+			return nil, err
+		}
 	}
 
-	loaded = cfg
+	if scripts == nil {
+		scripts = NewRegistry(DefaultRegistry)
+	}
+	return &Catalog{tools: cfg.Tools, Scripts: scripts, Pools: NewPools(cfg.Pool)}, nil
+}
+
+// check valida a configuração da tool ao carregar o tools.yml.
+func (t ToolConfig) check() error {
+	if err := t.checkParams(); err != nil {
+		return err
+	}
+	if _, err := newRowFormatter(t.Format, nil); err != nil {
+		return fmt.Errorf("tool %s: %w", t.Name, err)
+	}
+	if len(t.Facts) > 0 && (t.Format == FormatMarkdown || t.Format == FormatCSV) {
+		return fmt.Errorf("tool %s: facts só lê os formatos kv e jsonl", t.Name)
+	}
+	if t.Type == "postgres_embedding" {
+		if _, ok := distanceOps[t.Distance]; !ok {
+			return fmt.Errorf("tool %s: distance %q desconhecida (use cosine, l2 ou inner_product)", t.Name, t.Distance)
+		}
+		if len(t.Params) == 0 && t.placeholderCount() > 1 {
+			return fmt.Errorf("tool %s: filtros com $2 ou mais exigem params", t.Name)
+		}
+	}
 	return nil
 }
 
-func GetTool(name string) *ToolConfig {
-	for i := range loaded.Tools {
-		if loaded.Tools[i].Name == name {
-			return &loaded.Tools[i]
+func (c *Catalog) GetTool(name string) *ToolConfig {
+	if c == nil {
+		return nil
+	}
+	for i := range c.tools {
+		if c.tools[i].Name == name {
+			return &c.tools[i]
 		}
 	}
 	return nil
 }
+
+func (c *Catalog) All() []ToolConfig {
+	if c == nil {
+		return nil
+	}
+	out := make([]ToolConfig, len(c.tools))
+	copy(out, c.tools)
+	return out
+}
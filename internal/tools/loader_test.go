@@ -0,0 +1,15 @@
+package tools
+
+import "testing"
+
+func TestCheckFactsFormat(t *testing.T) {
+	base := ToolConfig{Name: "t", Type: "postgres", QueryTemplate: "SELECT 1",
+		Facts: []FactConfig{{KeyColumn: "id", Fields: []string{"nome"}}}}
+	for format, wantErr := range map[string]bool{"": false, FormatKV: false, FormatJSONL: false, FormatMarkdown: true, FormatCSV: true} {
+		tc := base
+		tc.Format = format
+		if err := tc.check(); (err != nil) != wantErr {
+			t.Errorf("format %q: err = %v, quer erro %v", format, err, wantErr)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package tools
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tipos aceitos em params.
+const (
+	ParamString = "string"
+	ParamInt    = "int"
+	ParamNumber = "number"
+	ParamBool   = "bool"
+	ParamEnum   = "enum"
+	ParamDate   = "date"
+)
+
+const defaultDateFormat = "2006-01-02"
+
+// ParamConfig declara um parâmetro da tool. A ordem em params é a posição: o
+// primeiro é $1, o segundo $2...
+//
+//	params:
+//	  - name: cpf
+//	    type: string
+//	    pattern: '^\d{11}$'
+//	    required: true
+//	  - name: status
+//	    type: enum
+//	    values: [aberto, pago, vencido]
+//	    default: aberto
+type ParamConfig struct {
+	Name        string   `yaml:"name"`
+	Type        string   `yaml:"type"`
+	Description string   `yaml:"description,omitempty"`
+	Required    bool     `yaml:"required,omitempty"`
+	Default     any      `yaml:"default,omitempty"`
+	Pattern     string   `yaml:"pattern,omitempty"`
+	Values      []string `yaml:"values,omitempty"`
+	// Layout Go das datas; o padrão é 2006-01-02.
+	Format string `yaml:"format,omitempty"`
+}
+
+func (p ParamConfig) dateFormat() string {
+	if p.Format != "" {
+		return p.Format
+	}
+	return defaultDateFormat
+}
+
+// check valida a declaração do parâmetro ao carregar o tools.yml.
+func (p ParamConfig) check() error {
+	if p.Name == "" {
+		return fmt.Errorf("parâmetro sem name")
+	}
+	switch p.Type {
+	case "", ParamString, ParamInt, ParamNumber, ParamBool, ParamDate:
+	case ParamEnum:
+		if len(p.Values) == 0 {
+			return fmt.Errorf("parâmetro %s: enum sem values", p.Name)
+		}
+	default:
+		return fmt.Errorf("parâmetro %s: tipo %q desconhecido", p.Name, p.Type)
+	}
+	if p.Pattern != "" {
+		if _, err := regexp.Compile(p.Pattern); err != nil {
+			return fmt.Errorf("parâmetro %s: pattern inválido: %w", p.Name, err)
+		}
+	}
+	if p.Default != nil {
+		if _, err := p.coerce(p.Default); err != nil {
+			return fmt.Errorf("default inválido: %w", err)
+		}
+	}
+	return nil
+}
+
+// schema descreve o parâmetro em JSON schema para o function calling.
+func (p ParamConfig) schema() map[string]any {
+	desc := p.Description
+	s := map[string]any{}
+	switch p.Type {
+	case ParamInt:
+		s["type"] = "integer"
+	case ParamNumber:
+		s["type"] = "number"
+	case ParamBool:
+		s["type"] = "boolean"
+	case ParamEnum:
+		s["type"] = "string"
+		s["enum"] = p.Values
+	case ParamDate:
+		s["type"] = "string"
+		desc = strings.TrimSpace(desc + " (data no formato " + humanDateFormat(p.dateFormat()) + ")")
+	default:
+		s["type"] = "string"
+	}
+	if p.Pattern != "" {
+		s["pattern"] = p.Pattern
+	}
+	if p.Default != nil {
+		if d, err := p.coerce(p.Default); err == nil {
+			desc = strings.TrimSpace(desc + " (padrão: " + d + ")")
+		}
+	}
+	if desc != "" {
+		s["description"] = desc
+	}
+	return s
+}
+
+// coerce valida o valor recebido e o devolve na forma canônica usada pelas
+// tools: inteiros sem casas, bool como true/false e datas no formato declarado.
+func (p ParamConfig) coerce(v any) (string, error) {
+	switch p.Type {
+	case ParamInt:
+		switch x := v.(type) {
+		case float64:
+			if x != math.Trunc(x) {
+				return "", p.invalid(v, "um número inteiro")
+			}
+			return strconv.FormatInt(int64(x), 10), nil
+		case int:
+			return strconv.Itoa(x), nil
+		case string:
+			n, err := strconv.ParseInt(strings.TrimSpace(x), 10, 64)
+			if err != nil {
+				return "", p.invalid(v, "um número inteiro")
+			}
+			return strconv.FormatInt(n, 10), nil
+		}
+		return "", p.invalid(v, "um número inteiro")
+
+	case ParamNumber:
+		switch x := v.(type) {
+		case float64:
+			return strconv.FormatFloat(x, 'f', -1, 64), nil
+		case int:
+			return strconv.Itoa(x), nil
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(x), 64)
+			if err != nil {
+				return "", p.invalid(v, "um número")
+			}
+			return strconv.FormatFloat(f, 'f', -1, 64), nil
+		}
+		return "", p.invalid(v, "um número")
+
+	case ParamBool:
+		switch x := v.(type) {
+		case bool:
+			return strconv.FormatBool(x), nil
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(x))
+			if err != nil {
+				return "", p.invalid(v, "true ou false")
+			}
+			return strconv.FormatBool(b), nil
+		}
+		return "", p.invalid(v, "true ou false")
+
+	case ParamEnum:
+		s := argString(v)
+		if !slices.Contains(p.Values, s) {
+			return "", p.invalid(v, "um de: "+strings.Join(p.Values, ", "))
+		}
+		return s, nil
+
+	case ParamDate:
+		// yaml.v3 decodifica "default: 2024-01-01" como time.Time
+		if t, ok := v.(time.Time); ok {
+			return t.Format(p.dateFormat()), nil
+		}
+		s, ok := v.(string)
+		if !ok {
+			return "", p.invalid(v, "uma data no formato "+humanDateFormat(p.dateFormat()))
+		}
+		t, err := time.Parse(p.dateFormat(), strings.TrimSpace(s))
+		if err != nil {
+			return "", p.invalid(v, "uma data no formato "+humanDateFormat(p.dateFormat()))
+		}
+		return t.Format(p.dateFormat()), nil
+	}
+
+	s := argString(v)
+	if p.Pattern != "" {
+		re, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			return "", err
+		}
+		if !re.MatchString(s) {
+			return "", p.invalid(v, "um texto no padrão "+p.Pattern)
+		}
+	}
+	return s, nil
+}
+
+func (p ParamConfig) invalid(v any, want string) error {
+	return fmt.Errorf("parâmetro %s inválido: esperado %s, recebido %q", p.Name, want, argString(v))
+}
+
+// humanDateFormat traduz o layout Go para o formato que o modelo entende.
+func humanDateFormat(layout string) string {
+	return strings.NewReplacer("2006", "AAAA", "01", "MM", "02", "DD", "15", "hh", "04", "mm", "05", "ss").Replace(layout)
+}
+
+// checkParams valida os params da tool ao carregar o tools.yml.
+func (t ToolConfig) checkParams() error {
+	if len(t.Params) == 0 {
+		return nil
+	}
+	seen := map[string]bool{}
+	for _, p := range t.Params {
+		if err := p.check(); err != nil {
+			return fmt.Errorf("tool %s: %w", t.Name, err)
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("tool %s: parâmetro %s repetido", t.Name, p.Name)
+		}
+		seen[p.Name] = true
+	}
+	if n := t.placeholderCount(); n > len(t.Params) {
+		return fmt.Errorf("tool %s: usa $%d mas declara %d params", t.Name, n, len(t.Params))
+	}
+	return nil
+}
+
+// decodeParams lê os argumentos nomeados da function call na ordem de params,
+// aplicando defaults e validando cada valor.
+func (t ToolConfig) decodeParams(m map[string]any) ([]string, error) {
+	args := make([]string, len(t.Params))
+	var errs []string
+	for i, p := range t.Params {
+		v, ok := m[p.Name]
+		if !ok || v == nil || v == "" {
+			switch {
+			case p.Default != nil:
+				v = p.Default
+			case p.Required:
+				errs = append(errs, "parâmetro "+p.Name+" é obrigatório")
+				continue
+			default:
+				continue
+			}
+		}
+		s, err := p.coerce(v)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		args[i] = s
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("argumentos inválidos para %s: %s", t.Name, strings.Join(errs, "; "))
+	}
+	return args, nil
+}
+
+// CheckArgs valida argumentos posicionais (protocolo TOOL:) contra params.
+// Sem params, devolve args como vieram.
+func (t ToolConfig) CheckArgs(args []string) ([]string, error) {
+	if len(t.Params) == 0 {
+		return args, nil
+	}
+	m := make(map[string]any, len(args))
+	for i, a := range args {
+		if i < len(t.Params) {
+			m[t.Params[i].Name] = a
+		}
+	}
+	return t.decodeParams(m)
+}
+
+// QueryArgs converte os argumentos para o driver SQL. Um parâmetro opcional sem
+// valor vai como NULL. Só vão os que a query usa: um param pode existir apenas
+// para facts (key_arg) e o driver recusa argumentos a mais.
+func (t ToolConfig) QueryArgs(args []string) []any {
+	args = args[:min(len(args), countPlaceholders(t.QueryTemplate))]
+	out := make([]any, len(args))
+	for i, v := range args {
+		if v == "" && i < len(t.Params) && !t.Params[i].Required {
+			continue
+		}
+		out[i] = v
+	}
+	return out
+}
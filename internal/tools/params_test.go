@@ -0,0 +1,151 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParamCoerce(t *testing.T) {
+	tests := []struct {
+		name    string
+		p       ParamConfig
+		in      any
+		want    string
+		wantErr bool
+	}{
+		{"int de float inteiro", ParamConfig{Name: "n", Type: ParamInt}, 7.0, "7", false},
+		{"int de string", ParamConfig{Name: "n", Type: ParamInt}, " 42 ", "42", false},
+		{"int com casas", ParamConfig{Name: "n", Type: ParamInt}, 2.5, "", true},
+		{"int inválido", ParamConfig{Name: "n", Type: ParamInt}, "x", "", true},
+		{"number", ParamConfig{Name: "v", Type: ParamNumber}, "12.50", "12.5", false},
+		{"bool", ParamConfig{Name: "b", Type: ParamBool}, true, "true", false},
+		{"bool de string", ParamConfig{Name: "b", Type: ParamBool}, "false", "false", false},
+		{"bool inválido", ParamConfig{Name: "b", Type: ParamBool}, "sim", "", true},
+		{"enum", ParamConfig{Name: "s", Type: ParamEnum, Values: []string{"a", "b"}}, "b", "b", false},
+		{"enum fora", ParamConfig{Name: "s", Type: ParamEnum, Values: []string{"a", "b"}}, "c", "", true},
+		{"date", ParamConfig{Name: "d", Type: ParamDate}, "2024-02-03", "2024-02-03", false},
+		{"date inexistente", ParamConfig{Name: "d", Type: ParamDate}, "2024-02-30", "", true},
+		{"date de time.Time", ParamConfig{Name: "d", Type: ParamDate}, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), "2024-01-01", false},
+		{"date com format", ParamConfig{Name: "d", Type: ParamDate, Format: "02/01/2006"}, "03/02/2024", "03/02/2024", false},
+		{"pattern", ParamConfig{Name: "cpf", Pattern: `^\d{11}$`}, "12345678901", "12345678901", false},
+		{"pattern falha", ParamConfig{Name: "cpf", Pattern: `^\d{11}$`}, "123", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.p.coerce(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, quer erro %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, quer %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadToolsDateDefault(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tools.yml")
+	yml := `tools:
+  - name: q
+    type: postgres
+    query_template: "SELECT $1::date"
+    params:
+      - {name: since, type: date, default: 2024-01-01}
+`
+	if err := os.WriteFile(path, []byte(yml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := LoadTools(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args, err := c.GetTool("q").DecodeArgs(`{}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(args) != 1 || args[0] != "2024-01-01" {
+		t.Errorf("args = %q, quer [2024-01-01]", args)
+	}
+}
+
+func TestDecodeParams(t *testing.T) {
+	tc := ToolConfig{Name: "t", Type: "postgres", QueryTemplate: "SELECT $1, $2, $3", Params: []ParamConfig{
+		{Name: "cpf", Required: true},
+		{Name: "limit", Type: ParamInt, Default: 10},
+		{Name: "status", Type: ParamEnum, Values: []string{"aberto", "pago"}},
+	}}
+	tests := []struct {
+		raw     string
+		want    []string
+		wantErr bool
+	}{
+		{`{"cpf":"1"}`, []string{"1", "10", ""}, false},
+		{`{"cpf":"1","limit":3,"status":"pago"}`, []string{"1", "3", "pago"}, false},
+		{`{"limit":3}`, nil, true},
+		{`{"cpf":"1","status":"x"}`, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := tc.DecodeArgs(tt.raw)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, quer erro %v", tt.raw, err, tt.wantErr)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %q, quer %q", tt.raw, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: got %q, quer %q", tt.raw, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestQueryArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		tc   ToolConfig
+		args []string
+		want []any
+	}{
+		{
+			name: "opcional vazio vira NULL",
+			tc: ToolConfig{QueryTemplate: "SELECT $1, $2", Params: []ParamConfig{
+				{Name: "a", Required: true}, {Name: "b"},
+			}},
+			args: []string{"x", ""},
+			want: []any{"x", nil},
+		},
+		{
+			name: "param fora da query não vai para o driver",
+			tc: ToolConfig{QueryTemplate: "SELECT * FROM t WHERE id = $1", Params: []ParamConfig{
+				{Name: "id", Required: true}, {Name: "cliente"},
+			}},
+			args: []string{"7", "c1"},
+			want: []any{"7"},
+		},
+		{
+			name: "sem params",
+			tc:   ToolConfig{QueryTemplate: "SELECT $1"},
+			args: []string{"x", "sobra"},
+			want: []any{"x"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.tc.QueryArgs(tt.args)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, quer %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, quer %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package tools
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// PoolConfig limita o pool de conexões de um DSN. Zero mantém o padrão do
+// database/sql.
+type PoolConfig struct {
+	MaxOpenConns    int           `yaml:"max_open_conns,omitempty"`
+	MaxIdleConns    int           `yaml:"max_idle_conns,omitempty"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime,omitempty"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time,omitempty"`
+}
+
+// Or preenche os campos zerados com os de def.
+func (c PoolConfig) Or(def PoolConfig) PoolConfig {
+	if c.MaxOpenConns == 0 {
+		c.MaxOpenConns = def.MaxOpenConns
+	}
+	if c.MaxIdleConns == 0 {
+		c.MaxIdleConns = def.MaxIdleConns
+	}
+	if c.ConnMaxLifetime == 0 {
+		c.ConnMaxLifetime = def.ConnMaxLifetime
+	}
+	if c.ConnMaxIdleTime == 0 {
+		c.ConnMaxIdleTime = def.ConnMaxIdleTime
+	}
+	return c
+}
+
+func (c PoolConfig) apply(db *sql.DB) {
+	if c.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(c.MaxOpenConns)
+	}
+	if c.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(c.MaxIdleConns)
+	}
+	if c.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(c.ConnMaxLifetime)
+	}
+	if c.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(c.ConnMaxIdleTime)
+	}
+}
+
+// Pools mantém um *sql.DB por DSN, aberto na primeira tool que o usa e
+// reaproveitado pelas seguintes. O primeiro uso de um DSN define os limites do
+// pool.
+type Pools struct {
+	mu       sync.Mutex
+	defaults PoolConfig
+	dbs      map[string]*sql.DB
+	shared   map[string]bool
+	closed   bool
+}
+
+func NewPools(defaults PoolConfig) *Pools {
+	return &Pools{defaults: defaults, dbs: map[string]*sql.DB{}, shared: map[string]bool{}}
+}
+
+// FillDefaults completa os limites padrão ainda zerados com def; vale para
+// pools ainda não abertos.
+func (p *Pools) FillDefaults(def PoolConfig) {
+	p.mu.Lock()
+	p.defaults = p.defaults.Or(def)
+	p.mu.Unlock()
+}
+
+// Share registra um pool aberto por outra parte (ex.: o da memória) para o DSN.
+// Ele é usado pelas tools, mas não é fechado por Close.
+func (p *Pools) Share(dsn string, db *sql.DB) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.dbs[dsn]; ok || db == nil {
+		return
+	}
+	p.dbs[dsn] = db
+	p.shared[dsn] = true
+}
+
+// DB devolve o pool do DSN, abrindo-o com cfg (completado pelos defaults) se
+// ainda não existir.
+func (p *Pools) DB(dsn string, cfg PoolConfig) (*sql.DB, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil, errors.New("pools de conexão já fechados")
+	}
+	if db, ok := p.dbs[dsn]; ok {
+		return db, nil
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Or(p.defaults).apply(db)
+	p.dbs[dsn] = db
+	return db, nil
+}
+
+// Close fecha os pools abertos aqui; os registrados com Share ficam abertos.
+func (p *Pools) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var errs []error
+	for dsn, db := range p.dbs {
+		if !p.shared[dsn] {
+			errs = append(errs, db.Close())
+		}
+	}
+	p.dbs = map[string]*sql.DB{}
+	p.shared = map[string]bool{}
+	p.closed = true
+	return errors.Join(errs...)
+}
+
+// Ping abre o pool de cada tool postgres do catálogo e verifica a conexão.
+func (c *Catalog) Ping(ctx context.Context) error {
+	if c == nil {
+		return nil
+	}
+	checked := map[string]error{}
+	for _, t := range c.tools {
+		if t.Type != "postgres" && t.Type != "postgres_embedding" {
+			continue
+		}
+		err, ok := checked[t.Conn]
+		if !ok {
+			var db *sql.DB
+			db, err = c.Pools.DB(t.Conn, t.Pool)
+			if err == nil {
+				err = db.PingContext(ctx)
+			}
+			checked[t.Conn] = err
+		}
+		if err != nil {
+			return fmt.Errorf("tool %s: banco indisponível: %w", t.Name, err)
+		}
+	}
+	return nil
+}
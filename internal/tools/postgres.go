@@ -1,119 +1,267 @@
 package tools
 
 import (
+	"cmp"
 	"context"
 	"database/sql"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/RafaelZelak/agentkit/internal/openai"
+	"github.com/RafaelZelak/agentkit/internal/pgvec"
 
 	_ "github.com/lib/pq"
 )
 
-func ExecPostgres(ctx context.Context, cfg ToolConfig, args ...any) (string, error) {
-	db, err := sql.Open("postgres", cfg.Conn)
+// Limites padrão das tools postgres.
+const (
+	defaultStatementTimeout = 15 * time.Second
+	defaultMaxRows          = 200
+	defaultMaxOutputBytes   = 32 << 10
+)
+
+// execLimits resolve os limites da tool: zero usa o padrão e negativo desliga
+// (devolvido como 0).
+func (t ToolConfig) execLimits() (readOnly bool, timeout time.Duration, maxRows, maxBytes int) {
+	readOnly = t.ReadOnly == nil || *t.ReadOnly
+	timeout = cmp.Or(t.StatementTimeout, defaultStatementTimeout)
+	maxRows = cmp.Or(t.MaxRows, defaultMaxRows)
+	maxBytes = cmp.Or(t.MaxOutputBytes, defaultMaxOutputBytes)
+	return readOnly, max(timeout, 0), max(maxRows, 0), max(maxBytes, 0)
+}
+
+// ExecPostgres roda QueryTemplate em uma transação própria (somente leitura por
+// padrão) com statement_timeout, parando em max_rows linhas ou max_output_bytes
+// bytes de saída. Quando corta, termina a saída com uma linha avisando. As
+// linhas saem no format da tool (kv por padrão).
+func ExecPostgres(ctx context.Context, pools *Pools, cfg ToolConfig, args ...any) (string, error) {
+	return runQuery(ctx, pools, cfg, cfg.QueryTemplate, args, func(rows *sql.Rows) (string, error) {
+		_, _, maxRows, maxBytes := cfg.execLimits()
+		return formatRows(rows, cfg.Format, maxRows, maxBytes)
+	})
+}
+
+// runQuery executa query na transação da tool, com os limites de execLimits, e
+// entrega as linhas para render.
+func runQuery(ctx context.Context, pools *Pools, cfg ToolConfig, query string, args []any, render func(*sql.Rows) (string, error)) (string, error) {
+	db, err := pools.DB(cfg.Conn, cfg.Pool)
+	if err != nil {
+		return "", err
+	}
+	readOnly, timeout, _, _ := cfg.execLimits()
+
+	if timeout > 0 {
+		// folga para o servidor cancelar o statement antes do contexto
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout+2*time.Second)
+		defer cancel()
+	}
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: readOnly})
 	if err != nil {
 		return "", err
 	}
-	defer db.Close()
+	defer tx.Rollback()
+	if timeout > 0 {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", timeout.Milliseconds())); err != nil {
+			return "", err
+		}
+	}
 
-	rows, err := db.QueryContext(ctx, cfg.QueryTemplate, args...)
+	rows, err := tx.QueryContext(ctx, query, args...)
 	if err != nil {
 		return "", err
 	}
 	defer rows.Close()
 
-	cols, err := rows.Columns()
+	out, err := render(rows)
+	if err != nil {
+		return "", err
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	rows.Close()
+	if !readOnly {
+		if err := tx.Commit(); err != nil {
+			return "", err
+		}
+	}
+	return out, nil
+}
+
+// formatRows escreve as linhas no formato pedido até maxRows linhas ou
+// maxBytes bytes (0 = sem limite).
+func formatRows(rows *sql.Rows, format string, maxRows, maxBytes int) (string, error) {
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return "", err
+	}
+	cols := make([]string, len(types))
+	dbTypes := make([]string, len(types))
+	for i, ct := range types {
+		cols[i] = ct.Name()
+		dbTypes[i] = ct.DatabaseTypeName()
+	}
+	return renderRows(rows, cols, dbTypes, format, maxRows, maxBytes)
+}
+
+// rowSource é a parte de *sql.Rows que renderRows usa.
+type rowSource interface {
+	Next() bool
+	Scan(dest ...any) error
+}
+
+// renderRows formata as linhas de src aplicando max_rows e max_output_bytes.
+func renderRows(src rowSource, cols, dbTypes []string, format string, maxRows, maxBytes int) (string, error) {
+	f, err := newRowFormatter(format, cols)
 	if err != nil {
 		return "", err
 	}
 
-	results := ""
-	for rows.Next() {
-		colsData := make([]any, len(cols))
-		colsPtrs := make([]any, len(cols))
-		for i := range cols {
-			colsPtrs[i] = &colsData[i]
+	var (
+		sb        strings.Builder
+		n         int
+		truncated string
+	)
+	sb.WriteString(f.header())
+	vals := make([]any, len(cols))
+	ptrs := make([]any, len(cols))
+	for i := range vals {
+		ptrs[i] = &vals[i]
+	}
+	for src.Next() {
+		if maxRows > 0 && n == maxRows {
+			truncated = fmt.Sprintf("[resultado truncado: mais de %d linhas]", maxRows)
+			break
 		}
-		if err := rows.Scan(colsPtrs...); err != nil {
+		if err := src.Scan(ptrs...); err != nil {
 			return "", err
 		}
-		row := ""
-		for i, c := range cols {
-			row += fmt.Sprintf("%s=%v ", c, colsData[i])
+		for i, t := range dbTypes {
+			vals[i] = cellValue(vals[i], t)
+		}
+		row := f.row(vals)
+		if maxBytes > 0 && sb.Len()+len(row) > maxBytes {
+			if n == 0 {
+				sb.WriteString(cutUTF8(row, max(maxBytes-sb.Len(), 0)) + "\n")
+			}
+			truncated = fmt.Sprintf("[resultado truncado: limite de %d bytes, %d linhas exibidas]", maxBytes, max(n, 1))
+			break
 		}
-		results += row + "\n"
+		sb.WriteString(row)
+		n++
+	}
+	if n == 0 && truncated == "" {
+		return "Nenhum resultado encontrado.", nil
 	}
-	if results == "" {
-		results = "Nenhum resultado encontrado."
+	if truncated != "" {
+		sb.WriteString(truncated + "\n")
 	}
-	return results, nil
+	return sb.String(), nil
 }
 
-func ExecPostgresEmbedding(ctx context.Context, cli *openai.Client, cfg ToolConfig, query string) (string, error) {
-	if cfg.Table == "" || cfg.Column == "" || cfg.EmbeddingModel == "" {
+// cutUTF8 corta s em até n bytes sem partir um caractere.
+func cutUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// Operadores de distância do pgvector por nome em distance.
+var distanceOps = map[string]string{
+	"":              "<=>",
+	"cosine":        "<=>",
+	"l2":            "<->",
+	"inner_product": "<#>",
+}
+
+// scoreExpr converte a distância em score: similaridade para cosine (1 - d) e
+// inner_product (-d, já que <#> devolve o produto negado); em l2 é a própria
+// distância.
+func scoreExpr(distance, d string) string {
+	switch distance {
+	case "l2":
+		return d
+	case "inner_product":
+		return "-(" + d + ")"
+	}
+	return "1 - (" + d + ")"
+}
+
+// ExecPostgresEmbedding busca as linhas mais próximas da query. Com columns, a
+// saída traz essas colunas e o score no format da tool; sem, devolve só column
+// com os resultados separados por "---".
+func ExecPostgresEmbedding(ctx context.Context, pools *Pools, cli openai.Embedder, cfg ToolConfig, query string, args []string) (string, error) {
+	if cfg.Table == "" || (cfg.Column == "" && len(cfg.Columns) == 0) || cfg.EmbeddingModel == "" {
 		return "", fmt.Errorf("tool %s mal configurada: table/column/embedding_model obrigatórios", cfg.Name)
 	}
 	if cfg.TopK <= 0 {
 		cfg.TopK = 5
 	}
+	op, ok := distanceOps[cfg.Distance]
+	if !ok {
+		return "", fmt.Errorf("tool %s: distance %q desconhecida (use cosine, l2 ou inner_product)", cfg.Name, cfg.Distance)
+	}
+	embCol := cmp.Or(cfg.EmbeddingColumn, "embedding")
 
-	emb, err := cli.Embed(ctx, cfg.EmbeddingModel, query)
+	// os filtros são montados antes do embedding para falhar sem gastar a chamada
+	where := []string{embCol + " IS NOT NULL"}
+	var qargs []any
+	bind := func(v any) string {
+		qargs = append(qargs, v)
+		return "$" + strconv.Itoa(len(qargs)+1)
+	}
+	dist := fmt.Sprintf("%s %s $1::vector", embCol, op)
+	if cfg.MaxDistance != nil {
+		where = append(where, fmt.Sprintf("%s <= %s", dist, bind(*cfg.MaxDistance)))
+	}
+	clauses, err := filterClauses(cfg.Filters, args, Attributes(ctx), bind)
 	if err != nil {
-		return "", fmt.Errorf("erro ao gerar embedding: %w", err)
+		return "", fmt.Errorf("tool %s: %w", cfg.Name, err)
 	}
+	where = append(where, clauses...)
 
-	vec := encodeVector(emb)
-
-	db, err := sql.Open("postgres", cfg.Conn)
+	emb, err := cli.Embed(ctx, cfg.EmbeddingModel, query)
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("erro ao gerar embedding: %w", err)
 	}
-	defer db.Close()
+	qargs = append([]any{pgvec.Encode(emb)}, qargs...)
 
+	cols := cfg.Column
+	if len(cfg.Columns) > 0 {
+		cols = strings.Join(cfg.Columns, ", ") + ", " + scoreExpr(cfg.Distance, dist) + " AS score"
+	}
 	sqlQuery := fmt.Sprintf(`
 		SELECT %s
 		FROM %s
-		WHERE embedding IS NOT NULL
-		ORDER BY embedding <=> $1::vector
+		WHERE %s
+		ORDER BY %s
 		LIMIT %d
-	`, cfg.Column, cfg.Table, cfg.TopK)
+	`, cols, cfg.Table, strings.Join(where, " AND "), dist, cfg.TopK)
 
-	rows, err := db.QueryContext(ctx, sqlQuery, vec)
-	if err != nil {
-		return "", err
-	}
-	defer rows.Close()
-
-	var results []string
-	for rows.Next() {
-		var content string
-		if err := rows.Scan(&content); err != nil {
-			return "", err
+	return runQuery(ctx, pools, cfg, sqlQuery, qargs, func(rows *sql.Rows) (string, error) {
+		if len(cfg.Columns) > 0 {
+			_, _, _, maxBytes := cfg.execLimits()
+			return formatRows(rows, cfg.Format, 0, maxBytes)
 		}
-		results = append(results, content)
-	}
-
-	if len(results) == 0 {
-		return "Nenhum resultado encontrado.", nil
-	}
-
-	return strings.Join(results, "\n---\n"), nil
-}
-
-func encodeVector(v []float32) string {
-	if len(v) == 0 {
-		return "[]"
-	}
-	var sb strings.Builder
-	sb.WriteByte('[')
-	for i, x := range v {
-		if i > 0 {
-			sb.WriteByte(',')
+		var results []string
+		for rows.Next() {
+			var content string
+			if err := rows.Scan(&content); err != nil {
+				return "", err
+			}
+			results = append(results, content)
 		}
-		sb.WriteString(fmt.Sprintf("%f", x))
-	}
-	sb.WriteByte(']')
-	return sb.String()
+		if len(results) == 0 {
+			return "Nenhum resultado encontrado.", nil
+		}
+		return strings.Join(results, "\n---\n"), nil
+	})
 }
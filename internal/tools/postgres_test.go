@@ -0,0 +1,106 @@
+package tools
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExecLimits(t *testing.T) {
+	no := false
+	tests := []struct {
+		name         string
+		tc           ToolConfig
+		wantReadOnly bool
+		wantTimeout  time.Duration
+		wantRows     int
+		wantBytes    int
+	}{
+		{"zero usa o padrão", ToolConfig{}, true, defaultStatementTimeout, defaultMaxRows, defaultMaxOutputBytes},
+		{"valores explícitos", ToolConfig{StatementTimeout: time.Second, MaxRows: 10, MaxOutputBytes: 100}, true, time.Second, 10, 100},
+		{"negativo desliga", ToolConfig{StatementTimeout: -1, MaxRows: -1, MaxOutputBytes: -5}, true, 0, 0, 0},
+		{"read_only false", ToolConfig{ReadOnly: &no}, false, defaultStatementTimeout, defaultMaxRows, defaultMaxOutputBytes},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ro, timeout, rows, bytes := tt.tc.execLimits()
+			if ro != tt.wantReadOnly || timeout != tt.wantTimeout || rows != tt.wantRows || bytes != tt.wantBytes {
+				t.Errorf("got (%v, %v, %d, %d), quer (%v, %v, %d, %d)", ro, timeout, rows, bytes,
+					tt.wantReadOnly, tt.wantTimeout, tt.wantRows, tt.wantBytes)
+			}
+		})
+	}
+}
+
+func TestCutUTF8(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"abc", 5, "abc"},
+		{"abc", 2, "ab"},
+		{"ação", 2, "a"}, // "ç" ocupa os bytes 1 e 2
+		{"ação", 3, "aç"},
+		{"日本", 4, "日"},
+		{"日本", 0, ""},
+	}
+	for _, tt := range tests {
+		if got := cutUTF8(tt.s, tt.n); got != tt.want {
+			t.Errorf("cutUTF8(%q, %d) = %q, quer %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
+
+// fakeRows entrega linhas de uma coluna como o *sql.Rows.
+type fakeRows struct {
+	vals []any
+	i    int
+}
+
+func (r *fakeRows) Next() bool {
+	r.i++
+	return r.i <= len(r.vals)
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	*dest[0].(*any) = r.vals[r.i-1]
+	return nil
+}
+
+func TestRenderRows(t *testing.T) {
+	tests := []struct {
+		name     string
+		vals     []any
+		maxRows  int
+		maxBytes int
+		want     string
+	}{
+		{"sem linhas", nil, 10, 100, "Nenhum resultado encontrado."},
+		{"cabe tudo", []any{"a", "b"}, 10, 100, "c=a\nc=b\n"},
+		{"sem limites", []any{"a", "b", "c"}, 0, 0, "c=a\nc=b\nc=c\n"},
+		{
+			"corta por linhas", []any{"a", "b", "c"}, 2, 0,
+			"c=a\nc=b\n[resultado truncado: mais de 2 linhas]\n",
+		},
+		{"exatamente max_rows não avisa", []any{"a", "b"}, 2, 0, "c=a\nc=b\n"},
+		{
+			"corta por bytes", []any{"aaaa", "bbbb", "cccc"}, 0, 14,
+			"c=aaaa\nc=bbbb\n[resultado truncado: limite de 14 bytes, 2 linhas exibidas]\n",
+		},
+		{
+			"primeira linha maior que o limite sai cortada sem partir caractere", []any{"ããããã"}, 0, 6,
+			"c=ãã\n[resultado truncado: limite de 6 bytes, 1 linhas exibidas]\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderRows(&fakeRows{vals: tt.vals}, []string{"c"}, []string{"TEXT"}, FormatKV, tt.maxRows, tt.maxBytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, quer %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var placeholderRe = regexp.MustCompile(`\$(\d+)`)
+
+// countPlaceholders devolve o maior $N usado no template.
+func countPlaceholders(tpl string) int {
+	n := 0
+	for _, m := range placeholderRe.FindAllStringSubmatch(tpl, -1) {
+		if v, err := strconv.Atoi(m[1]); err == nil && v > n {
+			n = v
+		}
+	}
+	return n
+}
+
+// positionalCount é o número de argumentos posicionais da tool: os params
+// declarados ou, sem eles, o maior $N dos templates.
+func (t ToolConfig) positionalCount() int {
+	if len(t.Params) > 0 {
+		return len(t.Params)
+	}
+	return t.placeholderCount()
+}
+
+func (t ToolConfig) placeholderCount() int {
+	switch t.Type {
+	case "postgres":
+		return countPlaceholders(t.QueryTemplate)
+	case "script":
+		return countPlaceholders(t.Function)
+	case "postgres_embedding":
+		n := 0
+		for _, f := range t.Filters {
+			n = max(n, countPlaceholders(f))
+		}
+		return n
+	case "http":
+		n := max(countPlaceholders(t.URL), bodyPlaceholders(t.Body))
+		for _, v := range t.Headers {
+			n = max(n, countPlaceholders(v))
+		}
+		return n
+	}
+	return 0
+}
+
+// Parameters devolve o JSON schema dos argumentos da tool, usado no function calling.
+func (t ToolConfig) Parameters() map[string]any {
+	props := map[string]any{}
+	required := []string{}
+
+	switch {
+	case len(t.Params) > 0:
+		for _, p := range t.Params {
+			props[p.Name] = p.schema()
+			if p.Required {
+				required = append(required, p.Name)
+			}
+		}
+	case t.Type == "postgres_embedding":
+		props["query"] = map[string]any{
+			"type":        "string",
+			"description": "Texto da busca semântica",
+		}
+		required = append(required, "query")
+	default:
+		for i := 1; i <= t.positionalCount(); i++ {
+			name := "arg" + strconv.Itoa(i)
+			props[name] = map[string]any{
+				"type":        "string",
+				"description": fmt.Sprintf("Valor para $%d", i),
+			}
+			required = append(required, name)
+		}
+	}
+
+	return map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+// DecodeArgs converte os argumentos JSON de uma function call nos argumentos posicionais da tool.
+func (t ToolConfig) DecodeArgs(raw string) ([]string, error) {
+	var m map[string]any
+	if strings.TrimSpace(raw) != "" {
+		if err := json.Unmarshal([]byte(raw), &m); err != nil {
+			return nil, fmt.Errorf("argumentos inválidos para %s: %w", t.Name, err)
+		}
+	}
+
+	if len(t.Params) > 0 {
+		return t.decodeParams(m)
+	}
+
+	if t.Type == "postgres_embedding" {
+		q := argString(m["query"])
+		if q == "" {
+			return nil, nil
+		}
+		return []string{q}, nil
+	}
+
+	n := t.positionalCount()
+	args := make([]string, 0, n)
+	for i := 1; i <= n; i++ {
+		v, ok := m["arg"+strconv.Itoa(i)]
+		if !ok {
+			return nil, fmt.Errorf("argumento arg%d ausente para %s", i, t.Name)
+		}
+		args = append(args, argString(v))
+	}
+	return args, nil
+}
+
+// SearchQuery é o texto da busca semântica: o param "query" (ou o primeiro)
+// quando há params; sem eles, os argumentos juntos.
+func (t ToolConfig) SearchQuery(args []string) string {
+	if len(t.Params) > 0 {
+		if slices.ContainsFunc(t.Params, func(p ParamConfig) bool { return p.Name == "query" }) {
+			return t.ArgByName(args, "query")
+		}
+		if len(args) > 0 {
+			return args[0]
+		}
+		return ""
+	}
+	return strings.Join(args, " ")
+}
+
+func argString(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(x)
+	default:
+		js, _ := json.Marshal(x)
+		return string(js)
+	}
+}
+
+// ArgByName devolve o argumento posicional correspondente a um nome do schema
+// (um dos params, arg1, query) ou placeholder ($1).
+func (t ToolConfig) ArgByName(args []string, name string) string {
+	name = strings.TrimSpace(name)
+	idx := slices.IndexFunc(t.Params, func(p ParamConfig) bool { return p.Name == name })
+	switch {
+	case idx >= 0:
+	case name == "query":
+		idx = 0
+	case strings.HasPrefix(name, "$"):
+		if n, err := strconv.Atoi(name[1:]); err == nil {
+			idx = n - 1
+		}
+	case strings.HasPrefix(name, "arg"):
+		if n, err := strconv.Atoi(name[3:]); err == nil {
+			idx = n - 1
+		}
+	}
+	if idx < 0 || idx >= len(args) {
+		return ""
+	}
+	return args[idx]
+}
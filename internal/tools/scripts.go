@@ -3,15 +3,51 @@ package tools
 import (
 	"fmt"
 	"strings"
+	"sync"
 )
 
-var ScriptRegistry = map[string]func(args ...string) (string, error){}
+type ScriptFunc func(args ...string) (string, error)
+
+// Registry guarda os scripts que as tools do tipo script podem chamar. Um
+// registry com parent procura primeiro nos próprios scripts e depois no parent.
+type Registry struct {
+	mu      sync.RWMutex
+	scripts map[string]ScriptFunc
+	parent  *Registry
+}
+
+func NewRegistry(parent *Registry) *Registry {
+	return &Registry{
+		scripts: map[string]ScriptFunc{},
+		parent:  parent,
+	}
+}
+
+// DefaultRegistry recebe os scripts registrados via sdk.RegisterScript e é o
+// parent do registry de cada agent.
+var DefaultRegistry = NewRegistry(nil)
 
 func RegisterScript(name string, fn func(args ...string) (string, error)) {
-	ScriptRegistry[name] = fn
+	DefaultRegistry.Register(name, fn)
+}
+
+func (r *Registry) Register(name string, fn func(args ...string) (string, error)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.scripts[name] = fn
+}
+
+func (r *Registry) Lookup(name string) (ScriptFunc, bool) {
+	r.mu.RLock()
+	fn, ok := r.scripts[name]
+	r.mu.RUnlock()
+	if !ok && r.parent != nil {
+		return r.parent.Lookup(name)
+	}
+	return fn, ok
 }
 
-func ExecScript(cfg ToolConfig, args ...string) (string, error) {
+func ExecScript(reg *Registry, cfg ToolConfig, args ...string) (string, error) {
 	fnDecl := cfg.Function
 	for i, arg := range args {
 		ph := fmt.Sprintf("$%d", i+1)
@@ -21,7 +57,7 @@ func ExecScript(cfg ToolConfig, args ...string) (string, error) {
 	fnName := strings.SplitN(fnDecl, "(", 2)[0]
 	fnName = strings.TrimSpace(fnName)
 
-	fn, ok := ScriptRegistry[fnName]
+	fn, ok := reg.Lookup(fnName)
 	if !ok {
 		return "", fmt.Errorf("função '%s' não registrada no ScriptRegistry", fnName)
 	}
@@ -0,0 +1,18 @@
+package agentkit
+
+import "github.com/RafaelZelak/agentkit/internal/memory"
+
+// MemoryStore é o backend de memória das conversas. Use Config.MemoryBackend
+// para escolher um dos embutidos ou Config.Memory para passar o seu.
+type MemoryStore = memory.Store
+
+type HistoryItem = memory.HistoryItem
+
+// Fact é um dado estruturado da sessão extraído da saída de uma tool.
+type Fact = memory.Fact
+
+// Summary é o resumo incremental de uma sessão longa.
+type Summary = memory.Summary
+
+// SessionRoute é a rota atual de uma sessão em RouteAndRun.
+type SessionRoute = memory.SessionRoute
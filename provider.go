@@ -0,0 +1,22 @@
+package agentkit
+
+import "github.com/RafaelZelak/agentkit/internal/openai"
+
+// Provider é o LLM usado pelo agent (respostas e embeddings). Por padrão é o
+// client da OpenAI; implemente a interface para usar outro backend.
+type Provider = openai.Provider
+
+// StreamProvider é opcional: providers que o implementam têm streaming real em
+// Agent.RunStream.
+type StreamProvider = openai.StreamProvider
+
+// Tipos trocados com o Provider, no formato da Responses API.
+type (
+	ResponsesRequest    = openai.ResponsesRequest
+	ResponseEnvelope    = openai.ResponseEnvelope
+	ResponseStreamEvent = openai.StreamEvent
+	Message             = openai.Message
+	ContentItem         = openai.ContentItem
+	Tool                = openai.Tool
+	FunctionCall        = openai.FunctionCall
+)